@@ -223,7 +223,7 @@ echo -e "   • ${GREEN}${PACKAGE_NAME}${NC} - 发布包"
 echo
 echo -e "${CYAN}💡 使用说明:${NC}"
 echo -e "   • 开发测试: ${YELLOW}./gover --debug${NC}"
-echo -e "   • 快速模式: ${YELLOW}./gover --fast --skip-fetch${NC}"
+echo -e "   • 跳过 fetch: ${YELLOW}./gover --skip-fetch${NC}"
 echo -e "   • 查看版本: ${YELLOW}./gover --version${NC}"
 echo -e "   • 修复权限: ${YELLOW}./gover --fix-git${NC}"
 echo -e "   • 生产部署: 解压 ${YELLOW}${PACKAGE_NAME}${NC} 到目标服务器"
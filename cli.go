@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"gover/models"
+
+	"golang.org/x/term"
+)
+
+// runCommand 执行子命令，返回进程退出码
+func runCommand(args []string) int {
+	switch args[0] {
+	case "token":
+		return runTokenCommand(args[1:])
+	case "hash-password":
+		return runHashPasswordCommand(args[1:])
+	case "sessions":
+		return runSessionsCommand(args[1:])
+	case "gen-cert":
+		return runGenCertCommand(args[1:])
+	case "check-config":
+		return runCheckConfigCommand(args[1:])
+	case "rotate-secret":
+		return runRotateSecretCommand()
+	case "help":
+		printCommandUsage()
+		return 0
+	default:
+		fmt.Printf("❌ 未知命令: %s\n\n", args[0])
+		printCommandUsage()
+		return 2
+	}
+}
+
+// printCommandUsage 输出子命令帮助
+func printCommandUsage() {
+	fmt.Printf("用法: gover [选项] 或 gover <命令> [参数]\n\n")
+	fmt.Printf("命令:\n")
+	fmt.Printf("  token create -name <名称> [-projects a,b] [-read-only]  创建 API 令牌\n")
+	fmt.Printf("  token list                                             列出 API 令牌\n")
+	fmt.Printf("  token revoke <名称>                                    吊销 API 令牌\n")
+	fmt.Printf("  hash-password [-algo bcrypt|argon2id]                  生成配置文件使用的密码哈希\n")
+	fmt.Printf("  sessions list                                          列出已登录的会话\n")
+	fmt.Printf("  sessions revoke <会话ID前缀>                           吊销指定会话\n")
+	fmt.Printf("  sessions revoke-all [-user <用户名>]                   吊销所有会话（或指定用户的会话）\n")
+	fmt.Printf("  gen-cert [-host a,b] [-cert 文件] [-key 文件] [-days N]  生成自签名开发证书\n")
+	fmt.Printf("  check-config [配置文件]                                检查配置文件，列出所有问题\n")
+	fmt.Printf("  rotate-secret                                          轮换自动生成的会话密钥（已登录会话保持有效）\n")
+	fmt.Printf("\n配置文件默认为 config.yaml，可通过 gover -config <文件> <命令> 或 GOVER_CONFIG 环境变量指定\n")
+	fmt.Printf("运行 gover -h 查看服务启动选项\n")
+}
+
+// loadCommandConfig 为子命令加载配置文件
+func loadCommandConfig() (*models.Config, bool) {
+	config, err := models.LoadConfig(models.ConfigPath())
+	if err != nil {
+		fmt.Printf("❌ 加载配置文件失败: %v\n", err)
+		return nil, false
+	}
+	return config, true
+}
+
+// runTokenCommand 管理 API 令牌
+func runTokenCommand(args []string) int {
+	if len(args) == 0 {
+		printCommandUsage()
+		return 2
+	}
+
+	config, ok := loadCommandConfig()
+	if !ok {
+		return 1
+	}
+	tokenFile := config.TokenFilePath()
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+		name := fs.String("name", "", "令牌名称（必填）")
+		projects := fs.String("projects", "", "允许访问的项目，多个用逗号分隔，为空表示全部项目")
+		readOnly := fs.Bool("read-only", false, "只读令牌，不能执行检出和回退")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if *name == "" {
+			fmt.Printf("❌ 必须通过 -name 指定令牌名称\n")
+			return 2
+		}
+
+		tokens, err := models.LoadTokenFile(tokenFile)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		for _, token := range tokens {
+			if token.Name == *name && !token.Revoked {
+				fmt.Printf("❌ 令牌 %s 已存在，请先吊销或使用其他名称\n", *name)
+				return 1
+			}
+		}
+
+		plain, hash, err := models.GenerateAPIToken()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		for _, token := range append(append([]models.APIToken{}, config.APITokens...), tokens...) {
+			if strings.EqualFold(token.Hash, hash) {
+				fmt.Printf("❌ 生成的令牌与已有令牌 %s 的哈希重复，请重试\n", token.Name)
+				return 1
+			}
+		}
+
+		token := models.APIToken{
+			Name:      *name,
+			Hash:      hash,
+			ReadOnly:  *readOnly,
+			CreatedAt: time.Now(),
+		}
+		for _, project := range strings.Split(*projects, ",") {
+			if project = strings.TrimSpace(project); project != "" {
+				token.Projects = append(token.Projects, project)
+			}
+		}
+
+		if err := models.SaveTokenFile(tokenFile, append(tokens, token)); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+
+		fmt.Printf("✅ 已创建令牌 %s（保存在 %s）\n", *name, tokenFile)
+		fmt.Printf("🔑 %s\n", plain)
+		fmt.Printf("⚠️ 令牌只显示这一次，请妥善保存。使用方式: Authorization: Bearer <令牌>\n")
+		return 0
+
+	case "list":
+		tokens, err := models.LoadTokenFile(tokenFile)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		all := append(append([]models.APIToken{}, config.APITokens...), tokens...)
+		if len(all) == 0 {
+			fmt.Printf("📭 暂无 API 令牌\n")
+			return 0
+		}
+		for _, token := range all {
+			scope := "全部项目"
+			if len(token.Projects) > 0 {
+				scope = strings.Join(token.Projects, ",")
+			}
+			mode := "读写"
+			if token.ReadOnly {
+				mode = "只读"
+			}
+			status := "✅ 有效"
+			if token.Revoked {
+				status = "⛔ 已吊销"
+			}
+			fmt.Printf("%s  %-20s %-6s %s\n", status, token.Name, mode, scope)
+		}
+		return 0
+
+	case "revoke":
+		if len(args) < 2 {
+			fmt.Printf("❌ 请指定要吊销的令牌名称\n")
+			return 2
+		}
+		tokens, err := models.LoadTokenFile(tokenFile)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		revoked := false
+		for i := range tokens {
+			if tokens[i].Name == args[1] && !tokens[i].Revoked {
+				tokens[i].Revoked = true
+				revoked = true
+			}
+		}
+		if !revoked {
+			fmt.Printf("❌ 令牌文件 %s 中没有有效的令牌 %s（config.yaml 中的令牌请设置 revoked: true）\n", tokenFile, args[1])
+			return 1
+		}
+		if err := models.SaveTokenFile(tokenFile, tokens); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✅ 已吊销令牌 %s，运行中的服务会立即生效\n", args[1])
+		return 0
+
+	default:
+		fmt.Printf("❌ 未知的 token 子命令: %s\n", args[0])
+		printCommandUsage()
+		return 2
+	}
+}
+
+// runHashPasswordCommand 生成密码哈希，输出结果可直接填入配置文件的 password 字段
+func runHashPasswordCommand(args []string) int {
+	fs := flag.NewFlagSet("hash-password", flag.ContinueOnError)
+	algo := fs.String("algo", models.PasswordAlgoBcrypt, "哈希算法: bcrypt 或 argon2id")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 读取密码失败: %v\n", err)
+		return 1
+	}
+	if password == "" {
+		fmt.Fprintf(os.Stderr, "❌ 密码不能为空\n")
+		return 2
+	}
+
+	hash, err := models.HashPassword(password, *algo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	// 哈希单独输出到标准输出，便于脚本使用
+	fmt.Println(hash)
+	return 0
+}
+
+// readPassword 读取密码：终端中不回显并要求输入两次，否则从标准输入读取一行
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, "🔐 请输入密码: ")
+	first, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprint(os.Stderr, "🔐 请再次输入: ")
+	second, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(first) != string(second) {
+		return "", fmt.Errorf("两次输入的密码不一致")
+	}
+	return string(first), nil
+}
+
+// runSessionsCommand 管理服务端会话，修改会话文件后运行中的服务立即生效
+func runSessionsCommand(args []string) int {
+	if len(args) == 0 {
+		printCommandUsage()
+		return 2
+	}
+
+	config, ok := loadCommandConfig()
+	if !ok {
+		return 1
+	}
+	sessionFile := config.SessionFilePath()
+	records := models.NewSessionStore(sessionFile)
+
+	switch args[0] {
+	case "list":
+		list, err := records.List()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		if len(list) == 0 {
+			fmt.Printf("📭 暂无已登录的会话\n")
+			return 0
+		}
+		for _, record := range list {
+			fmt.Printf("%-12s  %-12s %-16s 最近活动 %s  过期 %s\n",
+				record.ShortID(), record.Username, record.IP,
+				record.LastActivity.Format("2006-01-02 15:04:05"),
+				record.ExpiresAt.Format("2006-01-02 15:04:05"))
+		}
+		return 0
+
+	case "revoke":
+		if len(args) < 2 || args[1] == "" {
+			fmt.Printf("❌ 请指定要吊销的会话 ID（可使用 sessions list 显示的前缀）\n")
+			return 2
+		}
+		list, err := records.List()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		var matched []models.SessionRecord
+		for i := range list {
+			if models.MatchSessionID(&list[i], args[1]) {
+				matched = append(matched, list[i])
+			}
+		}
+		if len(matched) == 0 {
+			fmt.Printf("❌ 没有找到会话 %s\n", args[1])
+			return 1
+		}
+		if len(matched) > 1 {
+			fmt.Printf("❌ 会话 ID 前缀 %s 匹配到 %d 个会话，请提供更长的前缀\n", args[1], len(matched))
+			return 1
+		}
+		id := matched[0].ID
+		if _, err := records.Delete(func(record *models.SessionRecord) bool { return record.ID == id }); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✅ 已吊销用户 %s 的会话 %s\n", matched[0].Username, matched[0].ShortID())
+		return 0
+
+	case "revoke-all":
+		fs := flag.NewFlagSet("sessions revoke-all", flag.ContinueOnError)
+		user := fs.String("user", "", "只吊销指定用户的会话")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		count, err := records.Delete(func(record *models.SessionRecord) bool {
+			return *user == "" || record.Username == *user
+		})
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✅ 已吊销 %d 个会话，相关用户需要重新登录\n", count)
+		return 0
+
+	default:
+		fmt.Printf("❌ 未知的 sessions 子命令: %s\n", args[0])
+		printCommandUsage()
+		return 2
+	}
+}
+
+// runCheckConfigCommand 严格检查配置文件，一次列出所有问题，有问题时返回非零退出码
+func runCheckConfigCommand(args []string) int {
+	path := models.ConfigPath()
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	config, err := models.ParseConfigFile(path)
+	if err != nil {
+		var problems models.ValidationErrors
+		if errors.As(err, &problems) {
+			fmt.Printf("❌ 配置文件 %s 有 %d 个问题:\n", path, len(problems))
+			for _, problem := range problems {
+				fmt.Printf("   - %s\n", problem.Error())
+			}
+		} else {
+			fmt.Printf("❌ 配置文件 %s 无效: %v\n", path, err)
+		}
+		return 1
+	}
+
+	fmt.Printf("✅ 配置文件 %s 有效：%d 个用户，%d 个项目\n", path, len(config.AllUsers()), len(config.Projects))
+	if warnings := config.Warnings(); len(warnings) > 0 {
+		fmt.Printf("⚠️ %d 个项目暂不可用（不影响启动）:\n", len(warnings))
+		for _, warning := range warnings {
+			fmt.Printf("   - %s\n", warning.Error())
+		}
+	}
+	return 0
+}
+
+// runRotateSecretCommand 轮换自动生成的会话签名密钥，运行中的服务自动使用新密钥
+func runRotateSecretCommand() int {
+	config, ok := loadCommandConfig()
+	if !ok {
+		return 1
+	}
+
+	grace, err := config.RotateSessionKey()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	fmt.Printf("✅ 已生成新的会话密钥: %s\n", config.SessionKeyFilePath())
+	fmt.Printf("💡 旧密钥在 %.0f 小时后失效，在此之前已登录的会话仍然有效\n", grace.Hours())
+	return 0
+}
+
+// runGenCertCommand 生成自签名证书，仅用于开发和测试环境
+func runGenCertCommand(args []string) int {
+	fs := flag.NewFlagSet("gen-cert", flag.ContinueOnError)
+	hosts := fs.String("host", "localhost,127.0.0.1", "证书包含的域名或 IP，多个用逗号分隔")
+	certFile := fs.String("cert", "cert.pem", "证书输出文件")
+	keyFile := fs.String("key", "key.pem", "私钥输出文件")
+	days := fs.Int("days", 365, "有效天数")
+	force := fs.Bool("force", false, "覆盖已存在的文件")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *days <= 0 {
+		fmt.Printf("❌ 有效天数必须大于 0\n")
+		return 2
+	}
+
+	if !*force {
+		for _, file := range []string{*certFile, *keyFile} {
+			if _, err := os.Stat(file); err == nil {
+				fmt.Printf("❌ 文件 %s 已存在，使用 -force 覆盖\n", file)
+				return 1
+			}
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		fmt.Printf("❌ 生成私钥失败: %v\n", err)
+		return 1
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		fmt.Printf("❌ 生成证书序列号失败: %v\n", err)
+		return 1
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Gover 开发证书"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(0, 0, *days),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range strings.Split(*hosts, ",") {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	if len(template.DNSNames) > 0 {
+		template.Subject.CommonName = template.DNSNames[0]
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		fmt.Printf("❌ 生成证书失败: %v\n", err)
+		return 1
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		fmt.Printf("❌ 编码私钥失败: %v\n", err)
+		return 1
+	}
+
+	if err := os.WriteFile(*certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		fmt.Printf("❌ 写入证书失败: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(*keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		fmt.Printf("❌ 写入私钥失败: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("✅ 已生成自签名证书 %s 和私钥 %s（有效期 %d 天）\n", *certFile, *keyFile, *days)
+	fmt.Printf("⚠️ 自签名证书仅用于开发测试，生产环境请使用受信任 CA 签发的证书\n")
+	return 0
+}
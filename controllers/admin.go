@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"fmt"
+	"gover/models"
+	"net/http"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// AdminController 管理员功能控制器
+type AdminController struct {
+	web.Controller
+}
+
+// requireAdmin 要求当前用户是管理员，未登录时重定向到登录页，非管理员返回 403
+func (c *AdminController) requireAdmin() *Identity {
+	identity := RequireAuth(&c.Controller)
+	if identity == nil {
+		return nil
+	}
+	if !identity.IsAdmin() {
+		c.CustomAbort(http.StatusForbidden, "需要管理员权限")
+	}
+	return identity
+}
+
+// readFlash 读取上一次操作的 flash 消息
+func (c *AdminController) readFlash() {
+	flash := web.ReadFromRequest(&c.Controller)
+	c.Data["Success"] = flash.Data["success"]
+	c.Data["Error"] = flash.Data["error"]
+}
+
+// Lockouts 显示登录失败与锁定记录
+func (c *AdminController) Lockouts() {
+	if c.requireAdmin() == nil {
+		return
+	}
+
+	userMax, ipMax, _, lockout := loginLimits()
+	c.readFlash()
+	c.Data["Lockouts"] = loginAttempts.list()
+	c.Data["UserMax"] = userMax
+	c.Data["IPMax"] = ipMax
+	c.Data["LockoutDuration"] = formatWait(lockout)
+	c.Data["Title"] = "登录锁定 - " + models.GetConfig().UI.Title
+	c.TplName = "admin/lockouts.html"
+}
+
+// ClearLockout 清除指定的登录锁定记录，key 为空时清除全部
+func (c *AdminController) ClearLockout() {
+	identity := c.requireAdmin()
+	if identity == nil {
+		return
+	}
+
+	key := c.GetString("key")
+	count := loginAttempts.clear(key)
+	if key == "" {
+		fmt.Printf("🔓 管理员 %s 清除了全部 %d 条登录锁定记录\n", identity.Name, count)
+	} else {
+		fmt.Printf("🔓 管理员 %s 清除了登录锁定记录 %s\n", identity.Name, key)
+	}
+
+	flash := web.NewFlash()
+	if count == 0 {
+		flash.Error("没有找到对应的锁定记录，可能已经过期")
+	} else {
+		flash.Success("已清除 %d 条锁定记录", count)
+	}
+	flash.Store(&c.Controller)
+	c.Redirect("/admin/lockouts", 302)
+}
+
+// Sessions 显示所有已登录的会话
+func (c *AdminController) Sessions() {
+	if c.requireAdmin() == nil {
+		return
+	}
+
+	c.readFlash()
+	records, err := sessionRecords().List()
+	if err != nil {
+		c.Data["Error"] = err.Error()
+	}
+
+	c.Data["Sessions"] = records
+	c.Data["CurrentSessionID"] = currentSessionID(c.Ctx.Request)
+	c.Data["Title"] = "会话管理 - " + models.GetConfig().UI.Title
+	c.TplName = "admin/sessions.html"
+}
+
+// RevokeSession 吊销指定会话
+func (c *AdminController) RevokeSession() {
+	identity := c.requireAdmin()
+	if identity == nil {
+		return
+	}
+
+	id := c.GetString("id")
+	flash := web.NewFlash()
+	count, err := sessionRecords().Delete(func(record *models.SessionRecord) bool {
+		return id != "" && record.ID == id
+	})
+	switch {
+	case err != nil:
+		flash.Error("吊销会话失败: %v", err)
+	case count == 0:
+		flash.Error("会话不存在或已过期")
+	default:
+		fmt.Printf("🚫 管理员 %s 吊销了会话 %.12s\n", identity.Name, id)
+		flash.Success("会话已吊销")
+	}
+	flash.Store(&c.Controller)
+	c.Redirect("/admin/sessions", 302)
+}
+
+// RevokeAllSessions 吊销除当前会话以外的所有会话，可通过 username 参数只吊销指定用户的会话
+func (c *AdminController) RevokeAllSessions() {
+	identity := c.requireAdmin()
+	if identity == nil {
+		return
+	}
+
+	current := currentSessionID(c.Ctx.Request)
+	username := c.GetString("username")
+	flash := web.NewFlash()
+	count, err := sessionRecords().Delete(func(record *models.SessionRecord) bool {
+		return record.ID != current && (username == "" || record.Username == username)
+	})
+	if err != nil {
+		flash.Error("吊销会话失败: %v", err)
+	} else {
+		fmt.Printf("🚫 管理员 %s 吊销了 %d 个会话\n", identity.Name, count)
+		flash.Success("已吊销 %d 个会话", count)
+	}
+	flash.Store(&c.Controller)
+	c.Redirect("/admin/sessions", 302)
+}
@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gover/models"
+	"net/http"
+	"strings"
+)
+
+// API 错误码
+const (
+	apiErrUnauthorized   = "unauthorized"
+	apiErrForbidden      = "forbidden"
+	apiErrBadRequest     = "bad_request"
+	apiErrNotFound       = "not_found"
+	apiErrConfirmation   = "confirmation_required"
+	apiErrDiscardConfirm = "discard_confirmation_required"
+	apiErrCheckoutFailed = "checkout_failed"
+	apiErrRolledBack     = "rolled_back"
+	apiErrProjectBusy    = "project_busy"
+	apiErrInternal       = "internal_error"
+)
+
+// APIError 统一的 JSON 错误对象
+type APIError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// APIProjectSummary 项目列表中的项目概要
+type APIProjectSummary struct {
+	Name          string `json:"name"`
+	Path          string `json:"path"`
+	Description   string `json:"description"`
+	WorkingMode   string `json:"working_mode"`
+	CurrentBranch string `json:"current_branch"`
+	CurrentTag    string `json:"current_tag"`
+	CurrentCommit string `json:"current_commit"`
+	Group         string `json:"group"`
+	Environment   string `json:"environment"`
+	Production    bool   `json:"production"`
+}
+
+// APIController /api/v1 接口控制器，复用 VersionController 的 Git 操作
+type APIController struct {
+	VersionController
+	identity *Identity         // 当前请求的身份
+	body     map[string]string // JSON 请求体解析结果
+}
+
+// Prepare 所有接口都要求认证（登录会话或 Bearer 令牌），未认证时返回 401 而不是重定向到登录页
+func (c *APIController) Prepare() {
+	c.identity = authenticateRequest(&c.Controller)
+	if c.identity == nil {
+		c.respondError(http.StatusUnauthorized, apiErrUnauthorized, "未登录、会话已过期或令牌无效")
+		c.StopRun()
+	}
+}
+
+// respond 输出成功响应
+func (c *APIController) respond(status int, data interface{}) {
+	c.Ctx.Output.SetStatus(status)
+	c.Data["json"] = map[string]interface{}{
+		"data": data,
+	}
+	c.ServeJSON()
+}
+
+// respondError 输出错误响应
+func (c *APIController) respondError(status int, code, message string) {
+	c.respondErrorWithDetails(status, code, message, nil)
+}
+
+// respondErrorWithDetails 输出带附加数据的错误响应
+func (c *APIController) respondErrorWithDetails(status int, code, message string, details interface{}) {
+	c.Ctx.Output.SetStatus(status)
+	c.Data["json"] = map[string]interface{}{
+		"error": APIError{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
+	}
+	c.ServeJSON()
+}
+
+// param 读取请求参数，支持表单、查询字符串和 JSON 请求体
+func (c *APIController) param(name string) string {
+	if value := c.GetString(name); value != "" {
+		return value
+	}
+
+	if c.body == nil {
+		c.body = map[string]string{}
+		if strings.HasPrefix(c.Ctx.Input.Header("Content-Type"), "application/json") && len(c.Ctx.Input.RequestBody) > 0 {
+			var raw map[string]interface{}
+			if err := json.Unmarshal(c.Ctx.Input.RequestBody, &raw); err == nil {
+				for key, value := range raw {
+					if str, ok := value.(string); ok {
+						c.body[key] = str
+					}
+				}
+			}
+		}
+	}
+	return c.body[name]
+}
+
+// projectFromPath 根据路由参数 :name 获取项目，不存在时直接返回 404
+func (c *APIController) projectFromPath() *models.Project {
+	name := c.Ctx.Input.Param(":name")
+	project := models.GetConfig().GetProjectByName(name)
+	if project == nil {
+		c.respondError(http.StatusNotFound, apiErrNotFound, fmt.Sprintf("项目 %s 不存在或未启用", name))
+		return nil
+	}
+	if !c.identity.CanAccessProject(project.Name) {
+		c.respondError(http.StatusForbidden, apiErrForbidden, fmt.Sprintf("无权访问项目 %s", name))
+		return nil
+	}
+	return project
+}
+
+// deployableProject 获取项目并检查是否有变更权限，生产环境项目还需要 confirm 参数等于项目名称
+func (c *APIController) deployableProject() *models.Project {
+	project := c.projectFromPath()
+	if project == nil {
+		return nil
+	}
+	if !c.identity.CanDeploy(project.Name) {
+		c.respondError(http.StatusForbidden, apiErrForbidden, fmt.Sprintf("无权对项目 %s 执行变更操作", project.Name))
+		return nil
+	}
+	if !productionConfirmed(*project, c.param("confirm")) {
+		c.respondError(http.StatusPreconditionRequired, apiErrConfirmation, productionConfirmMessage(*project))
+		return nil
+	}
+	return project
+}
+
+// ListProjects GET /api/v1/projects 列出所有启用的项目，支持 group 和 environment 参数筛选
+func (c *APIController) ListProjects() {
+	summaries := []APIProjectSummary{}
+	for _, project := range filterProjects(visibleProjects(c.identity), c.GetString("group"), c.GetString("environment")) {
+		info := c.loadProjectInfo(c.requestContext(), project)
+		summaries = append(summaries, APIProjectSummary{
+			Name:          project.Name,
+			Path:          project.Path,
+			Description:   project.Description,
+			WorkingMode:   info.WorkingMode,
+			CurrentBranch: info.CurrentBranch,
+			CurrentTag:    info.CurrentTag,
+			CurrentCommit: info.CurrentCommit,
+			Group:         project.Group,
+			Environment:   project.Environment,
+			Production:    project.IsProduction(),
+		})
+	}
+	c.respond(http.StatusOK, summaries)
+}
+
+// GetProject GET /api/v1/projects/:name 获取项目详情（标签、分支、工作模式）
+func (c *APIController) GetProject() {
+	project := c.projectFromPath()
+	if project == nil {
+		return
+	}
+
+	info, found := getProjectFromCache(project.Path)
+	if found {
+		info = applyProjectConfig(info, *project)
+	} else if !c.identity.CanDeploy(project.Name) {
+		// 刷新会执行 fetch 并占用项目锁，与 Refresh 一样需要部署权限；只读身份返回基本信息，完整信息由后台刷新获取
+		info = c.loadProjectInfo(c.requestContext(), *project)
+	} else if refreshed, err := c.refreshProjectLocked(c.requestContext(), *project, c.identity.DisplayName()); err == nil {
+		info = refreshed
+	} else {
+		// 项目正忙时先返回基本信息，操作完成后会刷新缓存
+		info = c.loadProjectInfo(c.requestContext(), *project)
+	}
+	if previous, err := models.LastSuccessfulDeploy(project.Name); err == nil && previous != nil && previous.FromCommit != "" {
+		info.PreviousRef = previous.FromRef
+	}
+	c.respond(http.StatusOK, info)
+}
+
+// Checkout POST /api/v1/projects/:name/checkout 检出指定的标签或分支
+func (c *APIController) Checkout() {
+	project := c.deployableProject()
+	if project == nil {
+		return
+	}
+
+	tag := c.param("tag")
+	branch := c.param("branch")
+	if tag == "" && branch == "" {
+		c.respondError(http.StatusBadRequest, apiErrBadRequest, "必须指定 tag 或 branch")
+		return
+	}
+	if tag != "" && branch != "" {
+		c.respondError(http.StatusBadRequest, apiErrBadRequest, "不能同时指定 tag 和 branch")
+		return
+	}
+
+	targetType, targetRef := "tag", tag
+	if branch != "" {
+		targetType, targetRef = "branch", branch
+	}
+	if err := checkRefName(targetRef); err != nil {
+		c.respondError(http.StatusBadRequest, apiErrBadRequest, fmt.Sprintf("%s名 %s 无效: %v", deployTargetLabel(targetType), targetRef, err))
+		return
+	}
+
+	unlock, ok := c.acquireProjectLock(*project, deployOperation(targetType, targetRef))
+	if !ok {
+		return
+	}
+	defer unlock()
+	if !c.checkDiscardConfirmed(*project) {
+		return
+	}
+
+	result := c.deployProject(c.deployContext(), *project, targetType, targetRef)
+	recordDeployHistory(c.identity.DisplayName(), "checkout", result)
+	c.respondDeploy(*project, result)
+}
+
+// Revert POST /api/v1/projects/:name/revert 回退到上一次部署之前的版本
+func (c *APIController) Revert() {
+	project := c.deployableProject()
+	if project == nil {
+		return
+	}
+
+	unlock, ok := c.acquireProjectLock(*project, "回退")
+	if !ok {
+		return
+	}
+	defer unlock()
+	if !c.checkDiscardConfirmed(*project) {
+		return
+	}
+
+	previous, err := models.LastSuccessfulDeploy(project.Name)
+	if err != nil {
+		c.respondError(http.StatusInternalServerError, apiErrInternal, err.Error())
+		return
+	}
+	if previous == nil || previous.FromCommit == "" {
+		c.respondError(http.StatusNotFound, apiErrNotFound, fmt.Sprintf("项目 %s 没有可回退的部署记录", project.Name))
+		return
+	}
+
+	result := c.revertProject(c.deployContext(), *project, previous)
+	recordDeployHistory(c.identity.DisplayName(), "revert", result)
+	c.respondDeploy(*project, result)
+}
+
+// acquireProjectLock 获取项目锁，失败时输出错误响应并返回 false
+func (c *APIController) acquireProjectLock(project models.Project, operation string) (func(), bool) {
+	unlock, err := lockProject(c.requestContext(), project, operation, c.identity.DisplayName())
+	if err != nil {
+		c.respondLockError(err)
+		return nil, false
+	}
+	return unlock, true
+}
+
+// checkDiscardConfirmed 检出会丢弃本地修改时需要 discard_changes=true，未确认时返回 428
+func (c *APIController) checkDiscardConfirmed(project models.Project) bool {
+	ok, message := discardConfirmed(c.requestContext(), project, c.param("discard_changes"))
+	if !ok {
+		c.respondError(http.StatusPreconditionRequired, apiErrDiscardConfirm, message)
+	}
+	return ok
+}
+
+// respondLockError 输出获取项目锁失败的错误，项目正忙时返回 409 并附带锁持有者信息
+func (c *APIController) respondLockError(err error) {
+	var busy *ProjectBusyError
+	if errors.As(err, &busy) {
+		c.respondErrorWithDetails(http.StatusConflict, apiErrProjectBusy, busy.Error(), busy.Holder)
+		return
+	}
+	c.respondError(http.StatusInternalServerError, apiErrInternal, err.Error())
+}
+
+// respondDeploy 根据部署结果返回对应的状态码
+func (c *APIController) respondDeploy(project models.Project, result *DeployResult) {
+	c.refreshAfterDeploy(project, result)
+
+	switch {
+	case result.Success:
+		c.respond(http.StatusOK, result)
+	case result.RolledBack:
+		c.respondErrorWithDetails(http.StatusConflict, apiErrRolledBack, result.Message, result)
+	default:
+		c.respondErrorWithDetails(http.StatusInternalServerError, apiErrCheckoutFailed, result.Message, result)
+	}
+}
+
+// Refresh POST /api/v1/projects/:name/refresh 刷新项目缓存
+func (c *APIController) Refresh() {
+	project := c.projectFromPath()
+	if project == nil {
+		return
+	}
+	// 刷新会执行 fetch 并占用项目锁，需要变更权限
+	if !c.identity.CanDeploy(project.Name) {
+		c.respondError(http.StatusForbidden, apiErrForbidden, fmt.Sprintf("无权刷新项目 %s", project.Name))
+		return
+	}
+
+	info, err := c.refreshProjectLocked(c.requestContext(), *project, c.identity.DisplayName())
+	if err != nil {
+		c.respondLockError(err)
+		return
+	}
+	c.respond(http.StatusOK, info)
+}
+
+// ProjectHistory GET /api/v1/projects/:name/history 获取项目的部署历史
+func (c *APIController) ProjectHistory() {
+	project := c.projectFromPath()
+	if project == nil {
+		return
+	}
+
+	c.respondHistory(project.Name)
+}
+
+// History GET /api/v1/history 获取所有项目的部署历史，支持 project 参数过滤
+func (c *APIController) History() {
+	c.respondHistory(c.GetString("project"))
+}
+
+// respondHistory 查询并返回部署历史
+func (c *APIController) respondHistory(project string) {
+	limit, err := c.GetInt("limit", 50)
+	if err != nil || limit < 0 {
+		c.respondError(http.StatusBadRequest, apiErrBadRequest, "limit 参数无效")
+		return
+	}
+
+	entries, err := models.QueryHistory(models.HistoryFilter{
+		Project: project,
+		User:    c.GetString("user"),
+		Result:  c.GetString("result"),
+	})
+	if err != nil {
+		c.respondError(http.StatusInternalServerError, apiErrInternal, err.Error())
+		return
+	}
+
+	entries = filterHistoryForIdentity(c.identity, entries)
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	c.respond(http.StatusOK, entries)
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gover/models"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// apiTestConfig 两个项目，令牌分别为：只能访问 app、只读、已吊销
+const apiTestConfig = `server:
+  port: 8080
+data_dir: %q
+users:
+  - username: "admin"
+    password: "$2a$10$uYhnbrCycfiMwwu4R9vuWOiM2CdBw7jondfbL4e3cDlbnblP6SaNK"
+    role: "admin"
+security:
+  session_timeout: 3600
+  session_secret: "abcdefabcdefabcdefabcdefabcdefab"
+projects:
+  - name: "app"
+    path: %q
+    enabled: true
+  - name: "web"
+    path: %q
+    enabled: true
+api_tokens:
+  - name: "app-only"
+    hash: %q
+    projects: ["app"]
+  - name: "reader"
+    hash: %q
+    read_only: true
+  - name: "old"
+    hash: %q
+    revoked: true
+`
+
+func TestAPITokenScopes(t *testing.T) {
+	scoped, scopedHash, _ := models.GenerateAPIToken()
+	reader, readerHash, _ := models.GenerateAPIToken()
+	revoked, revokedHash, _ := models.GenerateAPIToken()
+	useTestConfigData(t, fmt.Sprintf(apiTestConfig, t.TempDir(), t.TempDir(), t.TempDir(), scopedHash, readerHash, revokedHash))
+
+	handler := web.NewControllerRegister()
+	handler.Add("/api/v1/projects/:name/history", &APIController{}, web.WithRouterMethods(&APIController{}, "get:ProjectHistory"))
+	handler.Add("/api/v1/projects/:name/checkout", &APIController{}, web.WithRouterMethods(&APIController{}, "post:Checkout"))
+	handler.Add("/api/v1/projects/:name/refresh", &APIController{}, web.WithRouterMethods(&APIController{}, "post:Refresh"))
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		want     int
+		wantCode string
+	}{
+		{"限定项目的令牌访问允许的项目", http.MethodGet, "/api/v1/projects/app/history", scoped, http.StatusOK, ""},
+		{"限定项目的令牌访问其他项目", http.MethodGet, "/api/v1/projects/web/history", scoped, http.StatusForbidden, apiErrForbidden},
+		{"限定项目的令牌检出其他项目", http.MethodPost, "/api/v1/projects/web/checkout?tag=v1", scoped, http.StatusForbidden, apiErrForbidden},
+		{"只读令牌可以查看", http.MethodGet, "/api/v1/projects/web/history", reader, http.StatusOK, ""},
+		{"只读令牌不能检出", http.MethodPost, "/api/v1/projects/web/checkout?tag=v1", reader, http.StatusForbidden, apiErrForbidden},
+		{"只读令牌不能刷新", http.MethodPost, "/api/v1/projects/web/refresh", reader, http.StatusForbidden, apiErrForbidden},
+		{"已吊销的令牌", http.MethodGet, "/api/v1/projects/app/history", revoked, http.StatusUnauthorized, apiErrUnauthorized},
+		{"未知令牌", http.MethodGet, "/api/v1/projects/app/history", models.APITokenPrefix + "unknown", http.StatusUnauthorized, apiErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("状态码 %d，期望 %d，响应: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var resp struct {
+				Error APIError `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error.Code != tt.wantCode {
+				t.Errorf("错误码为 %q，期望 %q，响应: %s", resp.Error.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
@@ -4,23 +4,28 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"gover/models"
+	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
 	"github.com/beego/beego/v2/server/web"
-	"github.com/gorilla/sessions"
 )
 
 var (
-	// Session store - 将在 init 中初始化
-	store *sessions.CookieStore
+	// Session store - 首次使用时按当前配置创建
+	store   *serverSessionStore
+	storeMu sync.Mutex
 )
 
 func init() {
 	// 延迟初始化 session store，等待配置加载
 }
 
-// ResetSessionStore 重置 Session Store（用于命令行清除功能）
+// ResetSessionStore 重置 Session Store，下次使用时按当前配置重新创建
 func ResetSessionStore() {
+	storeMu.Lock()
+	defer storeMu.Unlock()
 	store = nil
 }
 
@@ -29,17 +34,32 @@ type AuthController struct {
 	web.Controller
 }
 
-// initStore 初始化 session store
-func initStore() {
+// sessionStore 返回 session store，首次使用或重置后按当前配置创建
+func sessionStore() *serverSessionStore {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	config := models.GetConfig()
 	if store == nil {
-		store = sessions.NewCookieStore([]byte(models.AppConfig.Security.SessionSecret))
+		records := models.NewSessionStore(config.SessionFilePath())
+		store = newServerSessionStore(records)
+		// 以 HTTPS 提供服务时 Cookie 只通过 HTTPS 发送（TLS 配置需重启生效，以实际监听方式为准）
+		store.Options.Secure = web.BConfig.Listen.EnableHTTPS
+	}
+	// 首次创建或密钥文件被轮换（gover rotate-secret）后重新加载签名密钥
+	if modTime := config.SessionKeysModTime(); store.Codecs == nil || !modTime.Equal(store.keysModTime) {
+		keys, err := config.SessionKeys()
+		if err != nil {
+			panic(fmt.Sprintf("加载会话密钥失败: %v", err))
+		}
+		store.setKeys(keys)
+		store.keysModTime = config.SessionKeysModTime()
 	}
+	return store
 }
 
 // Login 显示登录页面或处理登录请求
 func (c *AuthController) Login() {
-	initStore()
-
 	if c.Ctx.Request.Method == "GET" {
 		// 检查是否已经登录
 		if c.isLoggedIn() {
@@ -50,7 +70,7 @@ func (c *AuthController) Login() {
 		// 获取重定向参数
 		redirect := c.GetString("redirect", "")
 
-		c.Data["Title"] = "登录 - " + models.AppConfig.UI.Title
+		c.Data["Title"] = "登录 - " + models.GetConfig().UI.Title
 		c.Data["Redirect"] = redirect
 		c.TplName = "auth/login.html"
 		return
@@ -61,25 +81,45 @@ func (c *AuthController) Login() {
 	password := c.GetString("password")
 	remember := c.GetString("remember") == "on"
 
-	if c.validateCredentials(username, password) {
+	// 防暴力破解：处于退避或锁定期间直接拒绝，不校验密码；校验前先占用尝试机会，并发请求不能绕过限制
+	ip := clientIP(&c.Controller)
+	if wait := loginAttempts.reserve(ip, username); wait > 0 {
+		c.Ctx.Output.SetStatus(http.StatusTooManyRequests)
+		c.loginError(fmt.Sprintf("登录尝试过于频繁，请 %s后再试", formatWait(wait)), username)
+		// 设置了状态码后 Beego 不会自动渲染模板，需要手动渲染
+		if err := c.Render(); err != nil {
+			fmt.Printf("渲染登录页面失败: %v\n", err)
+		}
+		return
+	}
+
+	// 使用校验时的用户配置生成配置哈希，期间热重载删除或修改了该用户时，会话会在下次请求时失效
+	user := c.validateCredentials(username, password)
+	loginAttempts.release(ip, username, user != nil)
+
+	if user != nil {
+
 		// 登录成功，创建 session
-		session, _ := store.Get(c.Ctx.Request, "gogo-session")
+		sessions := sessionStore()
+		session, _ := sessions.Get(c.Ctx.Request, "gogo-session")
+		sessions.renewSessionID(session)
 		session.Values["authenticated"] = true
 		session.Values["username"] = username
 		session.Values["login_time"] = time.Now().Unix()
-		session.Values["config_hash"] = c.getConfigHash() // 添加配置哈希
+		session.Values["config_hash"] = c.getConfigHash(user) // 添加配置哈希
+		rotateCSRFToken(session.Values)
 
 		// 设置 session 过期时间
 		if remember {
-			session.Options.MaxAge = models.AppConfig.Security.RememberMeDays * 24 * 3600
+			session.Options.MaxAge = models.GetConfig().Security.RememberMeDays * 24 * 3600
 		} else {
-			session.Options.MaxAge = models.AppConfig.Security.SessionTimeout
+			session.Options.MaxAge = models.GetConfig().Security.SessionTimeout
 		}
 
 		if err := session.Save(c.Ctx.Request, c.Ctx.ResponseWriter); err != nil {
 			c.Data["Error"] = "会话保存失败"
 			c.Data["Username"] = username
-			c.Data["Title"] = "登录 - " + models.AppConfig.UI.Title
+			c.Data["Title"] = "登录 - " + models.GetConfig().UI.Title
 			c.Data["Redirect"] = c.GetString("redirect", "")
 			c.TplName = "auth/login.html"
 			return
@@ -90,19 +130,22 @@ func (c *AuthController) Login() {
 		c.Redirect(redirect, 302)
 	} else {
 		// 登录失败
-		redirect := c.GetString("redirect", "")
-		c.Data["Error"] = "用户名或密码错误"
-		c.Data["Username"] = username
-		c.Data["Title"] = "登录 - " + models.AppConfig.UI.Title
-		c.Data["Redirect"] = redirect
-		c.TplName = "auth/login.html"
+		c.loginError("用户名或密码错误", username)
 	}
 }
 
+// loginError 显示带错误信息的登录页面
+func (c *AuthController) loginError(message, username string) {
+	c.Data["Error"] = message
+	c.Data["Username"] = username
+	c.Data["Title"] = "登录 - " + models.GetConfig().UI.Title
+	c.Data["Redirect"] = c.GetString("redirect", "")
+	c.TplName = "auth/login.html"
+}
+
 // Logout 退出登录
 func (c *AuthController) Logout() {
-	initStore()
-	session, _ := store.Get(c.Ctx.Request, "gogo-session")
+	session, _ := sessionStore().Get(c.Ctx.Request, "gogo-session")
 	session.Values["authenticated"] = false
 	session.Options.MaxAge = -1 // 删除 session
 	if err := session.Save(c.Ctx.Request, c.Ctx.ResponseWriter); err != nil {
@@ -113,40 +156,29 @@ func (c *AuthController) Logout() {
 	c.Redirect("/login", 302)
 }
 
-// validateCredentials 验证用户凭据
-func (c *AuthController) validateCredentials(username, password string) bool {
-	// 使用配置文件中的用户名和密码
-	expectedUsername := models.AppConfig.Auth.Username
-	expectedPassword := models.AppConfig.Auth.Password
-
-	// 对密码进行哈希比较（更安全）
-	hashedPassword := c.hashPassword(password)
-	expectedHashedPassword := c.hashPassword(expectedPassword)
-
-	return username == expectedUsername && hashedPassword == expectedHashedPassword
-}
-
-// hashPassword 对密码进行哈希处理
-func (c *AuthController) hashPassword(password string) string {
-	hash := sha256.Sum256([]byte(password + "gogo-salt"))
-	return fmt.Sprintf("%x", hash)
+// validateCredentials 验证用户凭据（bcrypt/argon2id 哈希，常量时间比较），通过时返回用户配置，否则返回 nil
+func (c *AuthController) validateCredentials(username, password string) *models.UserConfig {
+	user, err := models.GetConfig().CheckUserPassword(username, password)
+	if err != nil {
+		// 配置中的密码不是受支持的哈希（如明文），拒绝登录
+		fmt.Printf("❌ 拒绝登录: %v\n", err)
+		return nil
+	}
+	return user
 }
 
-// getConfigHash 获取配置哈希值，用于检测配置变更
-func (c *AuthController) getConfigHash() string {
+// getConfigHash 获取用户相关配置的哈希值，用于检测配置变更
+// 只包含该用户自己的凭据，修改其他用户或轮换会话密钥不会使当前用户的会话失效
+func (c *AuthController) getConfigHash(user *models.UserConfig) string {
 	// 将关键配置信息组合成字符串进行哈希
-	configData := fmt.Sprintf("%s:%s:%s",
-		models.AppConfig.Auth.Username,
-		models.AppConfig.Auth.Password,
-		models.AppConfig.Security.SessionSecret)
+	configData := fmt.Sprintf("%s:%s", user.Username, user.Password)
 	hash := sha256.Sum256([]byte(configData))
 	return fmt.Sprintf("%x", hash)
 }
 
 // invalidateSession 使当前 session 失效
 func (c *AuthController) invalidateSession() {
-	initStore()
-	session, err := store.Get(c.Ctx.Request, "gogo-session")
+	session, err := sessionStore().Get(c.Ctx.Request, "gogo-session")
 	if err != nil {
 		return
 	}
@@ -162,8 +194,7 @@ func (c *AuthController) invalidateSession() {
 
 // isLoggedIn 检查用户是否已登录
 func (c *AuthController) isLoggedIn() bool {
-	initStore()
-	session, err := store.Get(c.Ctx.Request, "gogo-session")
+	session, err := sessionStore().Get(c.Ctx.Request, "gogo-session")
 	if err != nil {
 		return false
 	}
@@ -180,7 +211,7 @@ func (c *AuthController) isLoggedIn() bool {
 	}
 
 	// 如果超过配置的超时时间，认为已过期
-	if time.Now().Unix()-loginTime > int64(models.AppConfig.Security.SessionTimeout) {
+	if time.Now().Unix()-loginTime > int64(models.GetConfig().Security.SessionTimeout) {
 		return false
 	}
 
@@ -191,7 +222,15 @@ func (c *AuthController) isLoggedIn() bool {
 		return false
 	}
 
-	currentConfigHash := c.getConfigHash()
+	// 用户已被删除时，session 失效
+	username, _ := session.Values["username"].(string)
+	user := models.GetConfig().FindUser(username)
+	if user == nil {
+		c.invalidateSession()
+		return false
+	}
+
+	currentConfigHash := c.getConfigHash(user)
 	if sessionConfigHash != currentConfigHash {
 		// 配置已变更，session 失效
 		c.invalidateSession()
@@ -201,13 +240,30 @@ func (c *AuthController) isLoggedIn() bool {
 	return true
 }
 
-// RequireAuth 中间件：要求用户登录
-func RequireAuth(c *web.Controller) {
+// currentUsername 获取当前登录的用户名，未登录时返回空字符串
+func currentUsername(c *web.Controller) string {
+	session, err := sessionStore().Get(c.Ctx.Request, "gogo-session")
+	if err != nil {
+		return ""
+	}
+	username, _ := session.Values["username"].(string)
+	return username
+}
+
+// isAuthenticated 检查请求是否已登录（不做重定向，供 JSON 接口使用）
+func isAuthenticated(c *web.Controller) bool {
 	authCtrl := &AuthController{Controller: *c}
-	if !authCtrl.isLoggedIn() {
+	return authCtrl.isLoggedIn()
+}
+
+// RequireAuth 中间件：要求用户登录，返回当前用户身份；未登录时重定向到登录页并返回 nil
+func RequireAuth(c *web.Controller) *Identity {
+	identity := sessionIdentity(c)
+	if identity == nil {
 		// 保存当前请求的 URL，登录后重定向
 		currentURL := c.Ctx.Request.URL.String()
-		c.Redirect("/login?redirect="+currentURL, 302)
-		return
+		c.Redirect("/login?redirect="+url.QueryEscape(currentURL), 302)
+		return nil
 	}
+	return identity
 }
@@ -0,0 +1,394 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"gover/models"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 克隆任务的状态
+const (
+	cloneStatusRunning = "running"
+	cloneStatusSuccess = "success"
+	cloneStatusFailed  = "failed"
+)
+
+const (
+	cloneTimeout    = 30 * time.Minute // 单次克隆的最长时间
+	cloneLogLines   = 50               // 每个任务保留的输出行数
+	cloneJobsToKeep = 20               // 保留的已结束任务数
+)
+
+// CloneRequest 从远程仓库克隆并注册项目的参数
+type CloneRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Remote      string `json:"remote"`
+	Path        string `json:"path"`
+	Group       string `json:"group"`
+	Environment string `json:"environment"`
+	Branch      string `json:"branch"`  // 只克隆指定分支（--single-branch），为空时克隆全部分支
+	Shallow     bool   `json:"shallow"` // 浅克隆（--depth 1）
+}
+
+// CloneJob 克隆任务
+type CloneJob struct {
+	ID         string       `json:"id"`
+	Request    CloneRequest `json:"request"`
+	Operator   string       `json:"operator"`
+	Status     string       `json:"status"`
+	Progress   string       `json:"progress"` // 最新的进度行，如 Receiving objects:  45% (450/1000)
+	Log        []string     `json:"log"`
+	Error      string       `json:"error,omitempty"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at,omitempty"`
+}
+
+// cloneJobs 内存中的克隆任务（重启后清空）
+var cloneJobs = struct {
+	sync.Mutex
+	jobs map[string]*CloneJob
+}{jobs: map[string]*CloneJob{}}
+
+// validateCloneRequest 检查克隆参数：项目名称未被使用，目标目录不存在或为空目录
+func validateCloneRequest(req CloneRequest) error {
+	if req.Name == "" {
+		return fmt.Errorf("项目名称不能为空")
+	}
+	for _, project := range models.GetConfig().Projects {
+		if project.Name == req.Name {
+			return fmt.Errorf("项目 %s 已存在", req.Name)
+		}
+	}
+	if !models.ValidEnvironment(req.Environment) {
+		return fmt.Errorf("无效的环境: %s", req.Environment)
+	}
+	if req.Remote == "" {
+		return fmt.Errorf("远程仓库地址不能为空")
+	}
+	if strings.HasPrefix(req.Remote, "-") || strings.HasPrefix(req.Branch, "-") {
+		return fmt.Errorf("远程仓库地址或分支名无效")
+	}
+	if !filepath.IsAbs(req.Path) {
+		return fmt.Errorf("目标路径必须是绝对路径: %s", req.Path)
+	}
+	if entries, err := os.ReadDir(req.Path); err == nil {
+		if len(entries) > 0 {
+			return fmt.Errorf("目标目录已存在且不为空: %s", req.Path)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("目标路径不可用: %v", err)
+	}
+	return nil
+}
+
+// startCloneJob 在后台启动克隆任务，同一项目名称或目标路径同时只能有一个任务
+func startCloneJob(req CloneRequest, operator string) (*CloneJob, error) {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	job := &CloneJob{
+		ID:        hex.EncodeToString(buf),
+		Request:   req,
+		Operator:  operator,
+		Status:    cloneStatusRunning,
+		StartedAt: time.Now(),
+	}
+
+	cloneJobs.Lock()
+	for _, running := range cloneJobs.jobs {
+		if running.Status == cloneStatusRunning && (running.Request.Name == req.Name || running.Request.Path == req.Path) {
+			cloneJobs.Unlock()
+			return nil, fmt.Errorf("已有正在进行的克隆任务使用了相同的项目名称或目标路径")
+		}
+	}
+	pruneCloneJobs()
+	cloneJobs.jobs[job.ID] = job
+	cloneJobs.Unlock()
+
+	go runCloneJob(job)
+	return job, nil
+}
+
+// runCloneJob 执行 git clone，成功后注册项目
+func runCloneJob(job *CloneJob) {
+	req := job.Request
+	fmt.Printf("⬇️ 管理员 %s 开始克隆 %s 到 %s\n", job.Operator, req.Remote, req.Path)
+
+	if err := cloneIntoPlace(job); err != nil {
+		finishCloneJob(job, fmt.Errorf("克隆失败: %v", err))
+		return
+	}
+
+	project := models.Project{
+		Name:        req.Name,
+		Path:        req.Path,
+		Description: req.Description,
+		Enabled:     true,
+		Group:       req.Group,
+		Environment: req.Environment,
+	}
+	if err := models.SaveProject("", project); err != nil {
+		// 仓库已克隆成功，保留目录以便手动处理
+		finishCloneJob(job, fmt.Errorf("仓库已克隆到 %s，但注册项目失败: %v", req.Path, err))
+		return
+	}
+	finishCloneJob(job, nil)
+}
+
+// cloneIntoPlace 先克隆到目标路径旁的临时目录，成功后再移动到目标路径
+// 失败时只删除临时目录，目标路径中已有的内容不受影响
+func cloneIntoPlace(job *CloneJob) error {
+	target := job.Request.Path
+	parent := filepath.Dir(target)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("创建上级目录失败: %v", err)
+	}
+	tmp, err := os.MkdirTemp(parent, "."+filepath.Base(target)+".clone-")
+	if err != nil {
+		return fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	// 临时目录默认只有所有者可以访问，改为与已存在的目标目录或新建目录相同的权限
+	mode := os.FileMode(0755)
+	if stat, err := os.Stat(target); err == nil {
+		mode = stat.Mode().Perm()
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("设置临时目录权限失败: %v", err)
+	}
+
+	if err := gitClone(job, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+
+	// 目标目录在校验时为空，os.Remove 只能删除空目录：期间被写入了内容时保留目标目录，放弃本次克隆
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("目标目录已不为空: %s", target)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("移动到目标路径失败: %v", err)
+	}
+	return nil
+}
+
+// gitClone 运行 git clone 克隆到 dir 并实时记录进度
+func gitClone(job *CloneJob, dir string) error {
+	req := job.Request
+	args := []string{"-c", "protocol.ext.allow=never", "clone", "--progress"}
+	if req.Shallow {
+		args = append(args, "--depth", "1")
+	}
+	if req.Branch != "" {
+		args = append(args, "--branch", req.Branch, "--single-branch")
+	}
+	args = append(args, "--", req.Remote, dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cloneTimeout)
+	defer cancel()
+
+	cmd := gitCommand(ctx, "", args...)
+	// 禁止交互式输入凭据，需要认证的仓库请使用 SSH 密钥或凭据助手
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(stderr)
+	scanner.Split(scanProgressLines)
+	for scanner.Scan() {
+		recordCloneOutput(job, scanner.Text())
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("超过 %s 未完成", cloneTimeout)
+		}
+		if last := lastCloneLine(job); last != "" {
+			return fmt.Errorf("%v: %s", err, last)
+		}
+		return err
+	}
+	return nil
+}
+
+// scanProgressLines 按换行或回车分割 git 输出
+func scanProgressLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// recordCloneOutput 记录一行输出：进度行只更新最新进度，其他行追加到日志
+func recordCloneOutput(job *CloneJob, line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	cloneJobs.Lock()
+	defer cloneJobs.Unlock()
+	job.Progress = line
+	if strings.Contains(line, "%") && !strings.Contains(line, "done") {
+		return
+	}
+	job.Log = append(job.Log, line)
+	if len(job.Log) > cloneLogLines {
+		job.Log = job.Log[len(job.Log)-cloneLogLines:]
+	}
+}
+
+// lastCloneLine 返回最后一行输出，通常是失败原因
+func lastCloneLine(job *CloneJob) string {
+	cloneJobs.Lock()
+	defer cloneJobs.Unlock()
+	if len(job.Log) == 0 {
+		return ""
+	}
+	return job.Log[len(job.Log)-1]
+}
+
+// finishCloneJob 结束任务
+func finishCloneJob(job *CloneJob, err error) {
+	cloneJobs.Lock()
+	defer cloneJobs.Unlock()
+
+	job.FinishedAt = time.Now()
+	if err != nil {
+		job.Status = cloneStatusFailed
+		job.Error = err.Error()
+		fmt.Printf("❌ 项目 %s: %v\n", job.Request.Name, err)
+		return
+	}
+	job.Status = cloneStatusSuccess
+	job.Progress = "完成"
+	fmt.Printf("✅ 已克隆 %s 并注册项目 %s\n", job.Request.Remote, job.Request.Name)
+}
+
+// getCloneJob 返回任务的副本
+func getCloneJob(id string) (CloneJob, bool) {
+	cloneJobs.Lock()
+	defer cloneJobs.Unlock()
+	job, ok := cloneJobs.jobs[id]
+	if !ok {
+		return CloneJob{}, false
+	}
+	copied := *job
+	copied.Log = append([]string{}, job.Log...)
+	return copied, true
+}
+
+// pruneCloneJobs 只保留最近结束的任务，调用方需持有锁
+func pruneCloneJobs() {
+	var finished []*CloneJob
+	for _, job := range cloneJobs.jobs {
+		if job.Status != cloneStatusRunning {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= cloneJobsToKeep {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.After(finished[j].FinishedAt) })
+	for _, job := range finished[cloneJobsToKeep:] {
+		delete(cloneJobs.jobs, job.ID)
+	}
+}
+
+// CloneProject 显示从远程仓库克隆项目的表单
+func (c *AdminController) CloneProject() {
+	if c.requireAdmin() == nil {
+		return
+	}
+	c.renderCloneForm(CloneRequest{}, "")
+}
+
+// StartClone 校验参数并在后台开始克隆，跳转到进度页面
+func (c *AdminController) StartClone() {
+	identity := c.requireAdmin()
+	if identity == nil {
+		return
+	}
+
+	req := CloneRequest{
+		Name:        strings.TrimSpace(c.GetString("name")),
+		Description: strings.TrimSpace(c.GetString("description")),
+		Remote:      strings.TrimSpace(c.GetString("remote")),
+		Path:        strings.TrimSpace(c.GetString("path")),
+		Group:       strings.TrimSpace(c.GetString("group")),
+		Environment: c.GetString("environment"),
+		Branch:      strings.TrimSpace(c.GetString("branch")),
+		Shallow:     c.GetString("shallow") == "on",
+	}
+	if err := validateCloneRequest(req); err != nil {
+		c.renderCloneForm(req, err.Error())
+		return
+	}
+
+	job, err := startCloneJob(req, identity.Name)
+	if err != nil {
+		c.renderCloneForm(req, err.Error())
+		return
+	}
+	c.Redirect("/admin/projects/clone/status?id="+job.ID, 302)
+}
+
+// CloneStatus 显示克隆进度页面
+func (c *AdminController) CloneStatus() {
+	if c.requireAdmin() == nil {
+		return
+	}
+	job, ok := getCloneJob(c.GetString("id"))
+	if !ok {
+		c.projectFlash("", "克隆任务不存在或已过期")
+		return
+	}
+	c.Data["Job"] = job
+	c.Data["Title"] = "克隆项目 - " + models.GetConfig().UI.Title
+	c.TplName = "admin/clone_status.html"
+}
+
+// CloneJobStatus 以 JSON 返回克隆任务状态，供进度页面轮询
+func (c *AdminController) CloneJobStatus() {
+	if c.requireAdmin() == nil {
+		return
+	}
+	job, ok := getCloneJob(c.GetString("id"))
+	if !ok {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		c.Data["json"] = map[string]interface{}{"success": false, "message": "克隆任务不存在或已过期"}
+	} else {
+		c.Data["json"] = job
+	}
+	c.ServeJSON()
+}
+
+// renderCloneForm 显示克隆表单，校验失败时保留用户输入
+func (c *AdminController) renderCloneForm(req CloneRequest, message string) {
+	c.Data["Clone"] = req
+	c.Data["Environments"] = models.Environments
+	c.Data["Error"] = message
+	c.Data["Title"] = "从远程仓库克隆 - " + models.GetConfig().UI.Title
+	c.TplName = "admin/project_clone.html"
+}
@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gover/models"
+)
+
+const testCloneConfig = `server:
+  port: 8080
+users:
+  - username: "admin"
+    password: "$2a$10$uYhnbrCycfiMwwu4R9vuWOiM2CdBw7jondfbL4e3cDlbnblP6SaNK"
+    role: "admin"
+security:
+  session_timeout: 3600
+projects: []
+`
+
+// runTestGit 在 dir 中执行 git 命令，失败时终止测试
+func runTestGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s 失败: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+// newBareRemote 创建包含一次提交的 git init --bare 远程仓库
+func newBareRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("未安装 git")
+	}
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	work := filepath.Join(root, "work")
+	runTestGit(t, root, "init", "--bare", "--initial-branch=main", remote)
+	runTestGit(t, root, "init", "--initial-branch=main", work)
+	if err := os.WriteFile(filepath.Join(work, "README"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, work, "add", "README")
+	runTestGit(t, work, "commit", "-m", "init")
+	runTestGit(t, work, "push", remote, "main")
+	return remote
+}
+
+// useTestConfig 让注册项目写入临时配置文件，测试结束后恢复原配置
+func useTestConfig(t *testing.T) string {
+	t.Helper()
+	return useTestConfigData(t, testCloneConfig)
+}
+
+// useTestConfigData 加载内容为 data 的临时配置文件，测试结束后恢复原配置
+func useTestConfigData(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	previousPath, previousConfig := models.ConfigPath(), models.GetConfig()
+	models.SetConfigPath(path)
+	if _, err := models.LoadConfig(path); err != nil {
+		t.Fatalf("加载测试配置失败: %v", err)
+	}
+	t.Cleanup(func() {
+		models.SetConfigPath(previousPath)
+		if previousConfig != nil {
+			models.LoadConfig(previousPath)
+		}
+	})
+	return path
+}
+
+func TestRunCloneJobSuccess(t *testing.T) {
+	remote := newBareRemote(t)
+	useTestConfig(t)
+	target := filepath.Join(t.TempDir(), "app")
+
+	job := &CloneJob{Request: CloneRequest{Name: "app", Remote: remote, Path: target}, Status: cloneStatusRunning}
+	runCloneJob(job)
+
+	if job.Status != cloneStatusSuccess {
+		t.Fatalf("克隆失败: %s", job.Error)
+	}
+	if data, err := os.ReadFile(filepath.Join(target, "README")); err != nil || string(data) != "hello\n" {
+		t.Errorf("克隆后的文件内容不正确: %q, %v", data, err)
+	}
+	project := models.GetConfig().GetProjectByName("app")
+	if project == nil || project.Path != target {
+		t.Errorf("克隆成功后应注册项目 app，实际为 %+v", project)
+	}
+}
+
+func TestRunCloneJobFailureCreatesNoDir(t *testing.T) {
+	useTestConfig(t)
+	root := t.TempDir()
+	target := filepath.Join(root, "app")
+
+	job := &CloneJob{Request: CloneRequest{Name: "app", Remote: filepath.Join(root, "missing.git"), Path: target}, Status: cloneStatusRunning}
+	runCloneJob(job)
+
+	if job.Status != cloneStatusFailed {
+		t.Fatal("远程仓库不存在时克隆应失败")
+	}
+	if !strings.Contains(job.Error, "克隆失败") {
+		t.Errorf("错误信息 %q 没有说明克隆失败", job.Error)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("克隆失败后不应创建目标目录 %s", target)
+	}
+	assertNoCloneTemp(t, target)
+	if models.GetConfig().GetProjectByName("app") != nil {
+		t.Error("克隆失败时不应注册项目")
+	}
+}
+
+// assertNoCloneTemp 检查目标路径旁没有遗留克隆用的临时目录
+func assertNoCloneTemp(t *testing.T, target string) {
+	t.Helper()
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".clone-*"))
+	if len(matches) > 0 {
+		t.Errorf("遗留了临时目录: %v", matches)
+	}
+}
+
+func TestRunCloneJobIntoExistingEmptyDir(t *testing.T) {
+	remote := newBareRemote(t)
+	useTestConfig(t)
+	target := filepath.Join(t.TempDir(), "app")
+	if err := os.Mkdir(target, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	job := &CloneJob{Request: CloneRequest{Name: "app", Remote: remote, Path: target}, Status: cloneStatusRunning}
+	runCloneJob(job)
+
+	if job.Status != cloneStatusSuccess {
+		t.Fatalf("克隆失败: %s", job.Error)
+	}
+	if _, err := os.Stat(filepath.Join(target, ".git")); err != nil {
+		t.Errorf("目标目录中没有克隆的仓库: %v", err)
+	}
+	if stat, err := os.Stat(target); err != nil {
+		t.Errorf("目标目录不可用: %v", err)
+	} else if stat.Mode().Perm() != 0750 {
+		t.Errorf("应保留目标目录原有的权限 0750，实际为 %v", stat.Mode().Perm())
+	}
+	assertNoCloneTemp(t, target)
+}
+
+func TestRunCloneJobFailureKeepsExistingDir(t *testing.T) {
+	remote := newBareRemote(t)
+	useTestConfig(t)
+	target := filepath.Join(t.TempDir(), "app")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// 分支不存在时克隆失败，已存在的目录应保留
+	job := &CloneJob{Request: CloneRequest{Name: "app", Remote: remote, Path: target, Branch: "missing"}, Status: cloneStatusRunning}
+	runCloneJob(job)
+
+	if job.Status != cloneStatusFailed {
+		t.Fatal("分支不存在时克隆应失败")
+	}
+	if stat, err := os.Stat(target); err != nil || !stat.IsDir() {
+		t.Fatalf("克隆失败后不应删除已存在的目录: %v", err)
+	}
+	assertNoCloneTemp(t, target)
+}
+
+func TestRunCloneJobKeepsFilesWrittenDuringClone(t *testing.T) {
+	remote := newBareRemote(t)
+	useTestConfig(t)
+	target := filepath.Join(t.TempDir(), "app")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 校验通过后目标目录又被写入了内容
+	live := filepath.Join(target, "live.txt")
+	if err := os.WriteFile(live, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	job := &CloneJob{Request: CloneRequest{Name: "app", Remote: remote, Path: target}, Status: cloneStatusRunning}
+	runCloneJob(job)
+
+	if job.Status != cloneStatusFailed {
+		t.Fatal("目标目录不为空时克隆应失败")
+	}
+	if data, err := os.ReadFile(live); err != nil || string(data) != "keep" {
+		t.Errorf("目标目录中已有的文件被修改或删除: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "README")); !os.IsNotExist(err) {
+		t.Error("目标目录不为空时不应写入克隆的内容")
+	}
+	if models.GetConfig().GetProjectByName("app") != nil {
+		t.Error("克隆失败时不应注册项目")
+	}
+	assertNoCloneTemp(t, target)
+}
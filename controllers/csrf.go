@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"gover/models"
+	"net/http"
+	"strings"
+
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// CSRF 相关常量
+const (
+	csrfSessionKey = "csrf_token"   // session 中保存令牌的键
+	csrfCookieName = "gover-csrf"   // 未登录时保存令牌的签名 Cookie
+	csrfFormField  = "_csrf"        // 表单字段名
+	csrfHeader     = "X-CSRF-Token" // fetch/AJAX 请求使用的请求头
+	csrfDataKey    = "CSRFToken"    // 模板中使用的变量名
+)
+
+// newCSRFToken 生成随机 CSRF 令牌
+func newCSRFToken() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("生成 CSRF 令牌失败: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// isSafeMethod 是否为不改变状态的请求方法
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// CSRFFilter CSRF 防护过滤器：为每个会话签发令牌并注入模板，校验所有 POST 等变更请求
+// 只有携带有效 Bearer 令牌的请求（API 调用）不需要 CSRF 令牌
+// 已登录时令牌保存在服务端会话中；未登录时保存在签名 Cookie 中，匿名请求不会创建服务端会话
+func CSRFFilter(ctx *context.Context) {
+	if token, ok := parseBearerToken(ctx.Input.Header("Authorization")); ok {
+		if isSafeMethod(ctx.Request.Method) || models.GetConfig().FindAPIToken(token) != nil {
+			return
+		}
+	}
+
+	// 会话 Cookie 无效（如密钥变更）时返回新会话，忽略错误
+	session, _ := sessionStore().Get(ctx.Request, "gogo-session")
+	expected, _ := session.Values[csrfSessionKey].(string)
+	if expected == "" {
+		expected = csrfCookieToken(ctx.Request)
+	}
+
+	if isSafeMethod(ctx.Request.Method) {
+		if expected == "" {
+			expected = newCSRFToken()
+			if err := setCSRFCookie(ctx.ResponseWriter, expected); err != nil {
+				fmt.Printf("保存 CSRF 令牌失败: %v\n", err)
+			}
+		}
+		ctx.Input.SetData(csrfDataKey, expected)
+		return
+	}
+
+	submitted := ctx.Input.Header(csrfHeader)
+	if submitted == "" {
+		submitted = ctx.Input.Query(csrfFormField)
+	}
+	if expected != "" && subtle.ConstantTimeCompare([]byte(submitted), []byte(expected)) == 1 {
+		ctx.Input.SetData(csrfDataKey, expected)
+		return
+	}
+
+	rejectCSRF(ctx)
+}
+
+// csrfCookieToken 读取签名 Cookie 中的 CSRF 令牌，Cookie 不存在或签名无效时返回空字符串
+func csrfCookieToken(r *http.Request) string {
+	cookie, err := r.Cookie(csrfCookieName)
+	if err != nil {
+		return ""
+	}
+	var token string
+	if err := securecookie.DecodeMulti(csrfCookieName, cookie.Value, &token, sessionStore().Codecs...); err != nil {
+		return ""
+	}
+	return token
+}
+
+// setCSRFCookie 将 CSRF 令牌签名后写入 Cookie，签名防止同站的其他子域名写入攻击者已知的令牌
+func setCSRFCookie(w http.ResponseWriter, token string) error {
+	store := sessionStore()
+	encoded, err := securecookie.EncodeMulti(csrfCookieName, token, store.Codecs...)
+	if err != nil {
+		return err
+	}
+	options := *store.Options
+	http.SetCookie(w, sessions.NewCookie(csrfCookieName, encoded, &options))
+	return nil
+}
+
+// rejectCSRF 返回 403，根据请求类型输出 JSON 或文本
+func rejectCSRF(ctx *context.Context) {
+	const message = "CSRF 校验失败，页面可能已过期，请刷新页面后重试"
+
+	ctx.Output.SetStatus(http.StatusForbidden)
+	switch {
+	case strings.HasPrefix(ctx.Request.URL.Path, "/api/"):
+		_ = ctx.Output.JSON(map[string]interface{}{
+			"error": APIError{Code: apiErrForbidden, Message: message},
+		}, false, false)
+	case ctx.Input.IsAjax() || ctx.Input.AcceptsJSON():
+		_ = ctx.Output.JSON(map[string]interface{}{
+			"success": false,
+			"message": message,
+		}, false, false)
+	default:
+		ctx.Output.Header("Content-Type", "text/plain; charset=utf-8")
+		_ = ctx.Output.Body([]byte("403 " + message))
+	}
+}
+
+// rotateCSRFToken 登录成功后更换 CSRF 令牌，防止登录前泄露的令牌继续有效
+func rotateCSRFToken(values map[interface{}]interface{}) {
+	values[csrfSessionKey] = newCSRFToken()
+}
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gover/models"
+
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+// csrfTestConfig 会话和令牌数据保存在临时目录中，api_tokens 包含一个有效令牌和一个已吊销的令牌
+const csrfTestConfig = `server:
+  port: 8080
+data_dir: %q
+users:
+  - username: "admin"
+    password: "$2a$10$uYhnbrCycfiMwwu4R9vuWOiM2CdBw7jondfbL4e3cDlbnblP6SaNK"
+    role: "admin"
+security:
+  session_timeout: 3600
+  session_secret: "abcdefabcdefabcdefabcdefabcdefab"
+api_tokens:
+  - name: "ci"
+    hash: %q
+  - name: "old"
+    hash: %q
+    revoked: true
+`
+
+// csrfTestServer 只经过 CSRFFilter 的测试服务：通过校验时返回注入模板的令牌
+// /login 模拟登录成功：创建已登录的会话并更换 CSRF 令牌
+type csrfTestServer struct {
+	*httptest.Server
+	client       *http.Client
+	validToken   string
+	revokedToken string
+}
+
+func newCSRFTestServer(t *testing.T) *csrfTestServer {
+	t.Helper()
+	validToken, validHash, _ := models.GenerateAPIToken()
+	revokedToken, revokedHash, _ := models.GenerateAPIToken()
+	useTestConfigData(t, fmt.Sprintf(csrfTestConfig, t.TempDir(), validHash, revokedHash))
+	ResetSessionStore()
+	t.Cleanup(ResetSessionStore)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.NewContext()
+		ctx.Reset(w, r)
+		CSRFFilter(ctx)
+		if ctx.ResponseWriter.Started {
+			return
+		}
+
+		if r.URL.Path == "/login" {
+			session, _ := sessionStore().Get(r, "gogo-session")
+			session.Values["authenticated"] = true
+			session.Values["username"] = "admin"
+			rotateCSRFToken(session.Values)
+			if err := session.Save(r, w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		token, _ := ctx.Input.GetData(csrfDataKey).(string)
+		io.WriteString(w, token)
+	})
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	jar, _ := cookiejar.New(nil)
+	return &csrfTestServer{
+		Server:       server,
+		client:       &http.Client{Jar: jar},
+		validToken:   validToken,
+		revokedToken: revokedToken,
+	}
+}
+
+// do 发送请求，返回状态码和响应体
+func (s *csrfTestServer) do(t *testing.T, method, path string, form url.Values, header map[string]string) (int, string) {
+	t.Helper()
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, s.URL+path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+// fetchToken 通过 GET 请求获取页面中的 CSRF 令牌
+func (s *csrfTestServer) fetchToken(t *testing.T) string {
+	t.Helper()
+	status, token := s.do(t, http.MethodGet, "/", nil, nil)
+	if status != http.StatusOK || token == "" {
+		t.Fatalf("GET 请求应签发 CSRF 令牌，状态码 %d，令牌 %q", status, token)
+	}
+	return token
+}
+
+func TestCSRFFilterAnonymousToken(t *testing.T) {
+	s := newCSRFTestServer(t)
+	token := s.fetchToken(t)
+
+	u, _ := url.Parse(s.URL)
+	var signed string
+	for _, cookie := range s.client.Jar.Cookies(u) {
+		switch cookie.Name {
+		case csrfCookieName:
+			signed = cookie.Value
+		case "gogo-session":
+			t.Error("未登录的请求不应创建服务端会话")
+		}
+	}
+	if signed == "" || signed == token {
+		t.Fatalf("令牌应保存在签名 Cookie 中，Cookie 值为 %q", signed)
+	}
+	if again := s.fetchToken(t); again != token {
+		t.Errorf("同一浏览器再次访问时令牌应保持不变: %q != %q", again, token)
+	}
+
+	tests := []struct {
+		name   string
+		form   url.Values
+		header map[string]string
+		want   int
+	}{
+		{"请求头携带令牌", nil, map[string]string{csrfHeader: token}, http.StatusOK},
+		{"表单字段携带令牌", url.Values{csrfFormField: {token}}, nil, http.StatusOK},
+		{"没有令牌", url.Values{"a": {"b"}}, nil, http.StatusForbidden},
+		{"令牌错误", nil, map[string]string{csrfHeader: token + "x"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _ := s.do(t, http.MethodPost, "/save", tt.form, tt.header); status != tt.want {
+				t.Errorf("状态码 %d，期望 %d", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFFilterRejectsForgedCookie(t *testing.T) {
+	s := newCSRFTestServer(t)
+	u, _ := url.Parse(s.URL)
+
+	// 同站的其他子域名可以写入 Cookie，但无法生成有效签名
+	s.client.Jar.SetCookies(u, []*http.Cookie{{Name: csrfCookieName, Value: "attacker-token", Path: "/"}})
+	if status, _ := s.do(t, http.MethodPost, "/save", nil, map[string]string{csrfHeader: "attacker-token"}); status != http.StatusForbidden {
+		t.Errorf("未签名的 CSRF Cookie 不应被接受，状态码 %d", status)
+	}
+
+	// 没有 Cookie 时任何令牌都无效
+	token := s.fetchToken(t)
+	s.client.Jar, _ = cookiejar.New(nil)
+	if status, _ := s.do(t, http.MethodPost, "/save", nil, map[string]string{csrfHeader: token}); status != http.StatusForbidden {
+		t.Errorf("没有 CSRF Cookie 时令牌不应被接受，状态码 %d", status)
+	}
+}
+
+func TestCSRFFilterBearerRequests(t *testing.T) {
+	s := newCSRFTestServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		header string
+		want   int
+	}{
+		{"有效令牌的 POST 不需要 CSRF 令牌", http.MethodPost, "Bearer " + s.validToken, http.StatusOK},
+		{"已吊销令牌的 POST 需要 CSRF 令牌", http.MethodPost, "Bearer " + s.revokedToken, http.StatusForbidden},
+		{"无效令牌的 POST 需要 CSRF 令牌", http.MethodPost, "Bearer gvr_invalid", http.StatusForbidden},
+		{"携带令牌的 GET 不受影响", http.MethodGet, "Bearer gvr_invalid", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _ := s.do(t, tt.method, "/api/v1/projects/app/checkout", nil, map[string]string{"Authorization": tt.header}); status != tt.want {
+				t.Errorf("状态码 %d，期望 %d", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFFilterAPIErrorFormat(t *testing.T) {
+	s := newCSRFTestServer(t)
+
+	status, body := s.do(t, http.MethodPost, "/api/v1/projects/app/checkout", nil, nil)
+	if status != http.StatusForbidden {
+		t.Fatalf("状态码 %d，期望 403", status)
+	}
+	var resp struct {
+		Error APIError `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil || resp.Error.Code != apiErrForbidden {
+		t.Errorf("API 请求应返回 JSON 错误，实际响应: %s", body)
+	}
+}
+
+func TestCSRFFilterRotatesTokenOnLogin(t *testing.T) {
+	s := newCSRFTestServer(t)
+	anonymous := s.fetchToken(t)
+
+	status, loggedIn := s.do(t, http.MethodPost, "/login", url.Values{csrfFormField: {anonymous}}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("登录请求被拒绝，状态码 %d", status)
+	}
+	if loggedIn != anonymous {
+		t.Fatalf("登录请求本身应使用登录前的令牌校验")
+	}
+
+	current := s.fetchToken(t)
+	if current == anonymous {
+		t.Fatal("登录后应更换 CSRF 令牌")
+	}
+	if status, _ := s.do(t, http.MethodPost, "/save", nil, map[string]string{csrfHeader: anonymous}); status != http.StatusForbidden {
+		t.Errorf("登录前的令牌在登录后不应继续有效，状态码 %d", status)
+	}
+	if status, _ := s.do(t, http.MethodPost, "/save", nil, map[string]string{csrfHeader: current}); status != http.StatusOK {
+		t.Errorf("登录后的令牌应被接受，状态码 %d", status)
+	}
+}
@@ -0,0 +1,394 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"gover/models"
+	"strings"
+	"time"
+)
+
+// DeployResult 一次检出（部署）操作的完整结果
+type DeployResult struct {
+	Project        string             `json:"project"`
+	TargetType     string             `json:"target_type"` // "tag"、"branch" 或 "commit"
+	TargetRef      string             `json:"target_ref"`
+	FromRef        string             `json:"from_ref"`    // 检出前的分支/标签/提交描述
+	FromCommit     string             `json:"from_commit"` // 检出前的完整提交哈希
+	FromBranch     string             `json:"from_branch"` // 检出前所在分支，游离状态时为空
+	ToCommit       string             `json:"to_commit"`
+	Success        bool               `json:"success"`
+	RolledBack     bool               `json:"rolled_back"`
+	RollbackReason string             `json:"rollback_reason,omitempty"`
+	RollbackError  string             `json:"rollback_error,omitempty"`
+	Stashed        bool               `json:"stashed,omitempty"`         // 检出前已将本地修改保存到 stash
+	StashError     string             `json:"stash_error,omitempty"`     // 检出后恢复本地修改失败的原因，修改仍保存在 stash 中
+	DiscardedFiles []string           `json:"discarded_files,omitempty"` // 检出前被丢弃修改的文件
+	Message        string             `json:"message"`
+	Steps          []HookResult       `json:"steps"`
+	HealthCheck    *HealthCheckResult `json:"health_check,omitempty"`
+	StartTime      time.Time          `json:"start_time"`
+	Duration       time.Duration      `json:"duration"`
+}
+
+// headState 记录检出前的 HEAD 状态，用于失败时回滚
+type headState struct {
+	Commit string // 完整提交哈希
+	Branch string // 所在分支，游离状态时为空
+	Ref    string // 便于阅读的描述
+}
+
+// captureHead 记录项目当前的 HEAD 状态
+func (c *VersionController) captureHead(ctx context.Context, project models.Project) (headState, error) {
+	head, err := gitBackendFor(project).Head(ctx, project.Path)
+	if err != nil {
+		return headState{}, err
+	}
+
+	state := headState{Commit: head.Commit, Branch: head.Branch}
+	switch {
+	case head.Branch != "":
+		state.Ref = head.Branch
+	case head.Tag != "":
+		state.Ref = head.Tag
+	default:
+		state.Ref = shortHash(head.Commit)
+	}
+	return state, nil
+}
+
+// restoreHead 将项目恢复到记录的 HEAD 状态
+func (c *VersionController) restoreHead(ctx context.Context, project models.Project, state headState) error {
+	backend := gitBackendFor(project)
+	if state.Branch == "" {
+		if err := backend.CheckoutCommit(ctx, project.Path, state.Commit); err != nil {
+			return fmt.Errorf("检出提交 %s 失败: %v", state.Commit, err)
+		}
+		return nil
+	}
+
+	// 分支在检出过程中可能已被 pull 更新，需要将其移回原提交
+	return backend.ResetBranch(ctx, project.Path, state.Branch, state.Commit)
+}
+
+// deployProject 检出指定的标签或分支
+func (c *VersionController) deployProject(ctx context.Context, project models.Project, targetType, targetRef string) *DeployResult {
+	return c.runDeploy(ctx, project, targetType, targetRef, func() error {
+		if targetType == "branch" {
+			return c.checkoutBranch(ctx, project, targetRef)
+		}
+		return c.checkoutTag(ctx, project, targetRef)
+	})
+}
+
+// revertProject 将项目恢复到某次部署之前的状态（分支或游离提交）
+func (c *VersionController) revertProject(ctx context.Context, project models.Project, entry *models.HistoryEntry) *DeployResult {
+	target := headState{
+		Commit: entry.FromCommit,
+		Branch: entry.FromBranch,
+		Ref:    entry.FromRef,
+	}
+	if target.Ref == "" {
+		target.Ref = entry.FromCommit
+	}
+
+	targetType := "commit"
+	if target.Branch != "" {
+		targetType = "branch"
+	}
+
+	return c.runDeploy(ctx, project, targetType, target.Ref, func() error {
+		return c.restoreHead(ctx, project, target)
+	})
+}
+
+// runDeploy 执行完整的检出流程：
+// 检出前步骤 -> 记录 HEAD -> 检出 -> 检出后步骤 -> 健康检查，后两步失败时自动回滚
+func (c *VersionController) runDeploy(ctx context.Context, project models.Project, targetType, targetRef string, checkout func() error) *DeployResult {
+	result := &DeployResult{
+		Project:    project.Name,
+		TargetType: targetType,
+		TargetRef:  targetRef,
+		StartTime:  time.Now(),
+	}
+	defer func() {
+		result.Duration = time.Since(result.StartTime)
+	}()
+
+	targetLabel := deployTargetLabel(targetType)
+
+	if err := project.PathError(); err != nil {
+		result.Message = fmt.Sprintf("项目 %s 不可用，已中止检出: %v", project.Name, err)
+		return result
+	}
+
+	hookEnv := map[string]string{
+		"GOVER_TARGET_TYPE": targetType,
+		"GOVER_TARGET_REF":  targetRef,
+	}
+
+	// 执行检出前步骤，任一步骤失败则中止检出
+	preResults, err := runHookSteps(project, "pre", project.PreCheckout, hookEnv)
+	result.Steps = append(result.Steps, preResults...)
+	if err != nil {
+		result.Message = fmt.Sprintf("项目 %s 已中止检出: %v", project.Name, err)
+		return result
+	}
+
+	// 记录检出前的 HEAD，用于失败时回滚
+	previous, err := c.captureHead(ctx, project)
+	if err != nil {
+		result.Message = fmt.Sprintf("项目 %s 无法记录当前版本，已中止检出: %v", project.Name, err)
+		return result
+	}
+	result.FromRef = previous.Ref
+	result.FromCommit = previous.Commit
+	result.FromBranch = previous.Branch
+	hookEnv["GOVER_PREVIOUS_REF"] = previous.Ref
+	hookEnv["GOVER_PREVIOUS_COMMIT"] = previous.Commit
+
+	// 按项目的策略处理工作区中未提交的修改
+	if err := c.prepareWorkingTree(ctx, project, targetLabel+" "+targetRef, result); err != nil {
+		result.Message = fmt.Sprintf("项目 %s 已中止检出: %v", project.Name, err)
+		return result
+	}
+	defer func() {
+		result.Message += workingTreeNote(result)
+	}()
+
+	err = checkout()
+	var stashErr error
+	if result.Stashed {
+		// 检出后立即恢复本地修改，检出后步骤（构建、重启等）需要使用这些修改
+		stashErr = c.restoreStash(ctx, project, result)
+	}
+	if err != nil {
+		result.Message = fmt.Sprintf("项目 %s 切换到%s %s 失败: %v", project.Name, targetLabel, targetRef, err)
+		return result
+	}
+
+	if head, err := gitBackendFor(project).Head(ctx, project.Path); err == nil {
+		result.ToCommit = head.Commit
+	}
+
+	// 本地修改无法应用到新版本时不能在缺少这些修改的工作区上执行检出后步骤，回到原来的版本
+	if stashErr != nil {
+		c.rollbackStash(ctx, project, previous, stashErr, result)
+		return result
+	}
+
+	// 执行检出后步骤，失败时回滚到检出前的版本
+	postResults, err := runHookSteps(project, "post", project.PostCheckout, hookEnv)
+	result.Steps = append(result.Steps, postResults...)
+	if err != nil {
+		c.rollbackDeploy(ctx, project, previous, err.Error(), hookEnv, result)
+		return result
+	}
+
+	// 执行健康检查，确认服务已正常启动
+	if project.HealthCheck != nil && project.HealthCheck.URL != "" {
+		result.HealthCheck = runHealthCheck(project.HealthCheck)
+		if !result.HealthCheck.Healthy {
+			c.rollbackDeploy(ctx, project, previous, result.HealthCheck.Summary(), hookEnv, result)
+			return result
+		}
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("项目 %s 成功切换到%s %s", project.Name, targetLabel, targetRef)
+	if result.HealthCheck != nil {
+		result.Message += "，" + result.HealthCheck.Summary()
+	}
+	return result
+}
+
+// prepareWorkingTree 检出前检查工作区，有未提交的修改时按项目的 dirty_policy 拒绝检出、stash 或丢弃修改
+// 未跟踪的文件（例如构建产物）不影响检出
+func (c *VersionController) prepareWorkingTree(ctx context.Context, project models.Project, target string, result *DeployResult) error {
+	backend := gitBackendFor(project)
+	status, err := backend.Status(ctx, project.Path)
+	if err != nil {
+		return err
+	}
+	if len(status.Modified) == 0 {
+		return nil
+	}
+
+	switch project.WorkingTreePolicy() {
+	case models.DirtyPolicyStash:
+		if err := backend.Stash(ctx, project.Path, fmt.Sprintf("gover: 检出%s 前自动保存", target)); err != nil {
+			return fmt.Errorf("保存本地修改到 stash 失败: %v", err)
+		}
+		result.Stashed = true
+		fmt.Printf("📦 项目 %s 已将 %d 个文件的本地修改保存到 stash\n", project.Name, len(status.Modified))
+	case models.DirtyPolicyReset:
+		if err := backend.DiscardChanges(ctx, project.Path); err != nil {
+			return fmt.Errorf("丢弃本地修改失败: %v", err)
+		}
+		result.DiscardedFiles = status.Modified
+		fmt.Printf("🧹 项目 %s 已丢弃 %d 个文件的本地修改: %s\n", project.Name, len(status.Modified), summarizeFiles(status.Modified))
+	default:
+		return fmt.Errorf("工作区有 %d 个文件未提交的修改（%s），请先处理这些修改，或将项目的 dirty_policy 设置为 stash 或 reset",
+			len(status.Modified), summarizeFiles(status.Modified))
+	}
+	return nil
+}
+
+// restoreStash 检出完成（或失败）后恢复检出前 stash 的本地修改，失败时修改保留在 stash 中并返回错误
+func (c *VersionController) restoreStash(ctx context.Context, project models.Project, result *DeployResult) error {
+	backend := gitBackendFor(project)
+	err := backend.StashPop(ctx, project.Path)
+	if err == nil {
+		return nil
+	}
+	result.StashError = err.Error()
+	fmt.Printf("⚠️ 项目 %s 恢复本地修改失败: %v\n", project.Name, err)
+
+	// 冲突时工作区会留下部分修改和冲突标记，这些修改都来自仍然保留的 stash，可以安全丢弃
+	if discardErr := backend.DiscardChanges(ctx, project.Path); discardErr != nil {
+		result.StashError += fmt.Sprintf("；清理冲突失败: %v", discardErr)
+		fmt.Printf("❌ 项目 %s 清理 stash 冲突失败: %v\n", project.Name, discardErr)
+	}
+	return err
+}
+
+// rollbackStash 本地修改无法应用到新版本时回滚到检出前的版本，并在原版本上重新恢复这些修改
+// 检出后步骤还没有执行，服务仍在运行原版本，不需要重新执行检出后步骤
+func (c *VersionController) rollbackStash(ctx context.Context, project models.Project, previous headState, stashErr error, result *DeployResult) {
+	reason := stashErr.Error()
+	result.RollbackReason = reason
+	fmt.Printf("⏪ 项目 %s 正在回滚到 %s，原因: %s\n", project.Name, previous.Ref, reason)
+
+	if err := c.restoreHead(ctx, project, previous); err != nil {
+		result.RollbackError = err.Error()
+		result.Message = fmt.Sprintf("项目 %s 部署失败（%s），且回滚到 %s 失败: %v", project.Name, reason, previous.Ref, err)
+		fmt.Printf("❌ 项目 %s 回滚失败: %v\n", project.Name, err)
+		return
+	}
+	result.RolledBack = true
+	result.Message = fmt.Sprintf("项目 %s 已回滚到 %s，原因: %s", project.Name, previous.Ref, reason)
+
+	// stash 是在原版本上创建的，回到原版本后可以直接恢复
+	if err := gitBackendFor(project).StashPop(ctx, project.Path); err != nil {
+		result.RollbackError = err.Error()
+		fmt.Printf("❌ 项目 %s 回滚后恢复本地修改失败: %v\n", project.Name, err)
+		return
+	}
+	result.StashError = ""
+}
+
+// workingTreeNote 返回检出前处理本地修改的说明，附加在部署结果消息之后
+func workingTreeNote(result *DeployResult) string {
+	switch {
+	case result.StashError != "":
+		return fmt.Sprintf("（恢复本地修改失败，修改仍保存在 stash 中: %s）", result.StashError)
+	case result.Stashed:
+		return "，已恢复检出前的本地修改"
+	case len(result.DiscardedFiles) > 0:
+		return fmt.Sprintf("，已丢弃 %d 个文件的本地修改", len(result.DiscardedFiles))
+	}
+	return ""
+}
+
+// summarizeFiles 返回文件列表的简短描述，最多列出 5 个文件
+func summarizeFiles(files []string) string {
+	const maxFiles = 5
+	if len(files) <= maxFiles {
+		return strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("%s 等 %d 个文件", strings.Join(files[:maxFiles], ", "), len(files))
+}
+
+// deployTargetLabel 返回检出目标类型的中文名称
+func deployTargetLabel(targetType string) string {
+	switch targetType {
+	case "branch":
+		return "分支"
+	case "commit":
+		return "提交"
+	default:
+		return "标签"
+	}
+}
+
+// deployOperation 返回检出操作的描述，用于项目锁的提示
+func deployOperation(targetType, targetRef string) string {
+	return fmt.Sprintf("检出%s %s", deployTargetLabel(targetType), targetRef)
+}
+
+// rollbackDeploy 将项目回滚到检出前的版本，并重新执行检出后步骤使旧版本生效
+func (c *VersionController) rollbackDeploy(ctx context.Context, project models.Project, previous headState, reason string, hookEnv map[string]string, result *DeployResult) {
+	result.RollbackReason = reason
+	fmt.Printf("⏪ 项目 %s 正在回滚到 %s，原因: %s\n", project.Name, previous.Ref, reason)
+
+	if err := c.restoreHead(ctx, project, previous); err != nil {
+		result.RollbackError = err.Error()
+		result.Message = fmt.Sprintf("项目 %s 部署失败（%s），且回滚到 %s 失败: %v", project.Name, reason, previous.Ref, err)
+		fmt.Printf("❌ 项目 %s 回滚失败: %v\n", project.Name, err)
+		return
+	}
+	result.RolledBack = true
+
+	// 旧版本同样需要构建/重启，尽力执行，失败仅记录
+	rollbackEnv := make(map[string]string, len(hookEnv)+1)
+	for key, value := range hookEnv {
+		rollbackEnv[key] = value
+	}
+	rollbackEnv["GOVER_ROLLBACK"] = "1"
+	rollbackResults, err := runHookSteps(project, "rollback", project.PostCheckout, rollbackEnv)
+	result.Steps = append(result.Steps, rollbackResults...)
+	if err != nil {
+		result.RollbackError = err.Error()
+	}
+
+	result.Message = fmt.Sprintf("项目 %s 已回滚到 %s，原因: %s", project.Name, previous.Ref, reason)
+	if result.RollbackError != "" {
+		result.Message += fmt.Sprintf("（回滚后重新执行检出后步骤失败: %s）", result.RollbackError)
+	}
+}
+
+// historyResult 将部署结果转换为历史记录的结果类型
+func (r *DeployResult) historyResult() string {
+	switch {
+	case r.Success:
+		return models.HistoryResultSuccess
+	case r.RolledBack:
+		return models.HistoryResultRolledBack
+	default:
+		return models.HistoryResultFailed
+	}
+}
+
+// recordDeployHistory 将部署结果写入部署历史
+func recordDeployHistory(user, action string, result *DeployResult) {
+	entry := models.HistoryEntry{
+		Time:       result.StartTime,
+		User:       user,
+		Project:    result.Project,
+		Action:     action,
+		TargetType: result.TargetType,
+		FromRef:    result.FromRef,
+		FromCommit: result.FromCommit,
+		FromBranch: result.FromBranch,
+		ToRef:      result.TargetRef,
+		ToCommit:   result.ToCommit,
+		Result:     result.historyResult(),
+		Message:    result.Message,
+		Duration:   result.Duration.Milliseconds(),
+	}
+	for _, step := range result.Steps {
+		entry.Steps = append(entry.Steps, models.HistoryStep{
+			Phase:    step.Phase,
+			Name:     step.Name,
+			Command:  step.Command,
+			ExitCode: step.ExitCode,
+			Output:   step.Output,
+			Error:    step.Error,
+			Duration: step.Duration.Milliseconds(),
+		})
+	}
+
+	if err := models.AppendHistory(entry); err != nil {
+		fmt.Printf("⚠️ 记录部署历史失败: %v\n", err)
+	}
+}
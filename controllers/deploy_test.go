@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gover/models"
+)
+
+const fakeGitBackend = "fake"
+
+// fakeBackend 在内存中模拟仓库状态的 GitBackend，记录调用顺序
+type fakeBackend struct {
+	head         GitHead
+	modified     []string
+	stashes      int
+	stashPopErrs []error // 依次作为 StashPop 的返回值，用完后恢复成功
+	calls        []string
+}
+
+func (f *fakeBackend) record(call string) {
+	f.calls = append(f.calls, call)
+}
+
+func (f *fakeBackend) Fetch(ctx context.Context, path string) error {
+	f.record("fetch")
+	return nil
+}
+
+func (f *fakeBackend) Tags(ctx context.Context, path string) ([]GitRef, error) {
+	return nil, nil
+}
+
+func (f *fakeBackend) Branches(ctx context.Context, path string) ([]GitRef, error) {
+	return nil, nil
+}
+
+func (f *fakeBackend) Head(ctx context.Context, path string) (GitHead, error) {
+	return f.head, nil
+}
+
+func (f *fakeBackend) CheckoutTag(ctx context.Context, path, tag string) error {
+	f.record("checkout-tag " + tag)
+	f.head = GitHead{Commit: "commit-" + tag, Tag: tag}
+	return nil
+}
+
+func (f *fakeBackend) CheckoutBranch(ctx context.Context, path, branch string) error {
+	f.record("checkout-branch " + branch)
+	f.head = GitHead{Commit: "commit-" + branch, Branch: branch}
+	return nil
+}
+
+func (f *fakeBackend) CheckoutCommit(ctx context.Context, path, commit string) error {
+	f.record("checkout-commit " + commit)
+	f.head = GitHead{Commit: commit}
+	return nil
+}
+
+func (f *fakeBackend) ResetBranch(ctx context.Context, path, branch, commit string) error {
+	f.record("reset-branch " + branch + " " + commit)
+	f.head = GitHead{Commit: commit, Branch: branch}
+	return nil
+}
+
+func (f *fakeBackend) Status(ctx context.Context, path string) (GitStatus, error) {
+	return GitStatus{Modified: f.modified, Stashes: f.stashes}, nil
+}
+
+func (f *fakeBackend) Stash(ctx context.Context, path, message string) error {
+	f.record("stash")
+	f.stashes++
+	return nil
+}
+
+func (f *fakeBackend) StashPop(ctx context.Context, path string) error {
+	f.record("stash-pop")
+	if len(f.stashPopErrs) > 0 {
+		err := f.stashPopErrs[0]
+		f.stashPopErrs = f.stashPopErrs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	f.stashes--
+	return nil
+}
+
+func (f *fakeBackend) DiscardChanges(ctx context.Context, path string) error {
+	f.record("discard")
+	return nil
+}
+
+// useFakeBackend 注册假 Git 后端，测试结束后移除
+func useFakeBackend(t *testing.T, backend *fakeBackend) {
+	t.Helper()
+	gitBackends[fakeGitBackend] = backend
+	t.Cleanup(func() {
+		delete(gitBackends, fakeGitBackend)
+	})
+}
+
+// deployFakeTag 使用假后端将项目检出到标签 tag
+func deployFakeTag(project models.Project, backend *fakeBackend, tag string) *DeployResult {
+	c := &VersionController{}
+	ctx := context.Background()
+	return c.runDeploy(ctx, project, "tag", tag, func() error {
+		return backend.CheckoutTag(ctx, project.Path, tag)
+	})
+}
+
+// requireShell 测试中的钩子命令使用 sh 语法
+func requireShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("钩子命令使用 sh 语法")
+	}
+}
+
+// stepPhases 返回各步骤的阶段，便于断言执行顺序
+func stepPhases(steps []HookResult) string {
+	phases := make([]string, len(steps))
+	for i, step := range steps {
+		phases[i] = step.Phase
+	}
+	return strings.Join(phases, ",")
+}
+
+func TestRunDeploySuccess(t *testing.T) {
+	requireShell(t)
+	backend := &fakeBackend{head: GitHead{Commit: "c1", Branch: "main"}}
+	useFakeBackend(t, backend)
+	project := models.Project{
+		Name:         "app",
+		Path:         t.TempDir(),
+		GitBackend:   fakeGitBackend,
+		PostCheckout: []models.HookStep{{Command: "true"}},
+	}
+
+	result := deployFakeTag(project, backend, "v2")
+
+	if !result.Success || result.RolledBack {
+		t.Fatalf("期望部署成功，实际: %s", result.Message)
+	}
+	if result.FromCommit != "c1" || result.FromBranch != "main" || result.ToCommit != "commit-v2" {
+		t.Errorf("版本记录不正确: from %s@%s，to %s", result.FromBranch, result.FromCommit, result.ToCommit)
+	}
+	if got := stepPhases(result.Steps); got != "post" {
+		t.Errorf("执行的步骤为 %q，期望 post", got)
+	}
+}
+
+func TestRunDeployPostHookFailureRollsBack(t *testing.T) {
+	requireShell(t)
+	backend := &fakeBackend{head: GitHead{Commit: "c1", Branch: "main"}}
+	useFakeBackend(t, backend)
+	project := models.Project{
+		Name:       "app",
+		Path:       t.TempDir(),
+		GitBackend: fakeGitBackend,
+		// 部署时失败，回滚后重新执行时成功
+		PostCheckout: []models.HookStep{{Name: "restart", Command: `test "$GOVER_ROLLBACK" = 1`}},
+	}
+
+	result := deployFakeTag(project, backend, "v2")
+
+	if result.Success {
+		t.Fatal("检出后步骤失败时部署不应成功")
+	}
+	if !result.RolledBack || result.RollbackError != "" {
+		t.Fatalf("期望回滚成功，实际 RolledBack=%v，RollbackError=%q", result.RolledBack, result.RollbackError)
+	}
+	if !strings.Contains(result.RollbackReason, "restart") {
+		t.Errorf("回滚原因 %q 没有说明失败的步骤", result.RollbackReason)
+	}
+	if backend.head.Commit != "c1" || backend.head.Branch != "main" {
+		t.Errorf("应回滚到 main@c1，实际为 %s@%s", backend.head.Branch, backend.head.Commit)
+	}
+	if !containsCall(backend.calls, "reset-branch main c1") {
+		t.Errorf("回滚应将分支移回原提交，实际调用: %v", backend.calls)
+	}
+	if got := stepPhases(result.Steps); got != "post,rollback" {
+		t.Errorf("执行的步骤为 %q，期望 post,rollback", got)
+	}
+	if result.historyResult() != models.HistoryResultRolledBack {
+		t.Errorf("历史记录结果为 %s，期望 %s", result.historyResult(), models.HistoryResultRolledBack)
+	}
+}
+
+func TestRunDeployStashPopFailureRollsBack(t *testing.T) {
+	requireShell(t)
+	backend := &fakeBackend{
+		head:         GitHead{Commit: "c1", Tag: "v1"},
+		modified:     []string{"config.yaml"},
+		stashPopErrs: []error{errors.New("CONFLICT (content): Merge conflict in config.yaml")},
+	}
+	useFakeBackend(t, backend)
+	path := t.TempDir()
+	project := models.Project{
+		Name:         "app",
+		Path:         path,
+		GitBackend:   fakeGitBackend,
+		DirtyPolicy:  models.DirtyPolicyStash,
+		PostCheckout: []models.HookStep{{Command: "touch built"}},
+	}
+
+	result := deployFakeTag(project, backend, "v2")
+
+	if result.Success {
+		t.Fatal("本地修改无法恢复时部署不应成功")
+	}
+	if !result.RolledBack || result.RollbackError != "" {
+		t.Fatalf("期望回滚成功，实际 RolledBack=%v，RollbackError=%q", result.RolledBack, result.RollbackError)
+	}
+	if !strings.Contains(result.RollbackReason, "CONFLICT") {
+		t.Errorf("回滚原因 %q 没有包含 stash 冲突信息", result.RollbackReason)
+	}
+	if !result.Stashed || result.StashError != "" {
+		t.Errorf("回滚后应在原版本上恢复本地修改，StashError=%q", result.StashError)
+	}
+	if backend.head.Commit != "c1" || backend.head.Branch != "" {
+		t.Errorf("应回滚到游离提交 c1，实际为 %s@%s", backend.head.Branch, backend.head.Commit)
+	}
+	if backend.stashes != 0 {
+		t.Errorf("回滚后 stash 应已恢复，剩余 %d 个", backend.stashes)
+	}
+	want := []string{"stash", "checkout-tag v2", "stash-pop", "discard", "checkout-commit c1", "stash-pop"}
+	if strings.Join(backend.calls, ";") != strings.Join(want, ";") {
+		t.Errorf("调用顺序为 %v，期望 %v", backend.calls, want)
+	}
+	if len(result.Steps) != 0 {
+		t.Errorf("本地修改无法恢复时不应执行检出后步骤，实际执行了 %s", stepPhases(result.Steps))
+	}
+	if _, err := os.Stat(filepath.Join(path, "built")); !os.IsNotExist(err) {
+		t.Error("本地修改无法恢复时不应执行检出后步骤")
+	}
+}
+
+func TestRunDeployStashPopFailureKeepsStash(t *testing.T) {
+	backend := &fakeBackend{
+		head:         GitHead{Commit: "c1", Branch: "main"},
+		modified:     []string{"config.yaml"},
+		stashPopErrs: []error{errors.New("conflict"), errors.New("still conflicting")},
+	}
+	useFakeBackend(t, backend)
+	project := models.Project{
+		Name:        "app",
+		Path:        t.TempDir(),
+		GitBackend:  fakeGitBackend,
+		DirtyPolicy: models.DirtyPolicyStash,
+	}
+
+	result := deployFakeTag(project, backend, "v2")
+
+	if result.Success || !result.RolledBack {
+		t.Fatalf("期望部署失败并回滚，实际 Success=%v，RolledBack=%v", result.Success, result.RolledBack)
+	}
+	if !strings.Contains(result.RollbackError, "still conflicting") {
+		t.Errorf("回滚错误 %q 没有说明恢复本地修改失败", result.RollbackError)
+	}
+	if result.StashError == "" || !strings.Contains(result.Message, "stash") {
+		t.Errorf("修改仍在 stash 中时应提示用户，消息: %s", result.Message)
+	}
+	if backend.stashes != 1 {
+		t.Errorf("恢复失败时 stash 应保留，实际剩余 %d 个", backend.stashes)
+	}
+}
+
+// containsCall 判断调用记录中是否包含 call
+func containsCall(calls []string, call string) bool {
+	for _, c := range calls {
+		if c == call {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"gover/models"
+	"strings"
+	"time"
+)
+
+// GitRef 标签或分支
+type GitRef struct {
+	Name    string    // 标签名或分支名，远程分支带远程名前缀，如 origin/main
+	Commit  string    // 指向的完整提交哈希
+	Remote  bool      // 是否为远程分支
+	Time    time.Time // 附注标签为打标签的时间，轻量标签和分支为指向的提交的时间
+	Message string    // 标签为备注（轻量标签为提交说明），分支为最后一次提交的标题
+}
+
+// GitHead 当前 HEAD 的状态
+type GitHead struct {
+	Commit   string // 完整提交哈希
+	Branch   string // 所在分支，游离状态时为空
+	Tag      string // 游离状态时正好位于的标签，没有时为空
+	Describe string // 游离状态且不在标签上时，最近标签的描述（如 v1.2.0-3-gabc1234），没有标签时为空
+}
+
+// GitStatus 工作区状态
+type GitStatus struct {
+	Modified  []string // 有修改（包括已暂存）的已跟踪文件
+	Untracked []string // 未跟踪的文件（不包括被忽略的文件）
+	Stashes   int      // stash 数量
+}
+
+// GitBackend Git 仓库操作，每个项目可以通过 git_backend 选择实现
+type GitBackend interface {
+	// Fetch 从所有远程仓库获取分支和标签
+	Fetch(ctx context.Context, path string) error
+	// Tags 按版本号降序返回全部标签及其详情
+	Tags(ctx context.Context, path string) ([]GitRef, error)
+	// Branches 返回全部本地分支和远程分支及其最后一次提交，本地分支在前
+	Branches(ctx context.Context, path string) ([]GitRef, error)
+	// Head 返回当前 HEAD 的状态
+	Head(ctx context.Context, path string) (GitHead, error)
+	// CheckoutTag 检出标签（游离状态）
+	CheckoutTag(ctx context.Context, path, tag string) error
+	// CheckoutBranch 切换到分支并拉取更新，本地不存在时从 origin 创建跟踪分支；branch 可以带 origin/ 前缀
+	CheckoutBranch(ctx context.Context, path, branch string) error
+	// CheckoutCommit 检出提交（游离状态）
+	CheckoutCommit(ctx context.Context, path, commit string) error
+	// ResetBranch 切换到分支并将其移动到指定提交，保留未提交的修改（等同于 git reset --keep）
+	ResetBranch(ctx context.Context, path, branch, commit string) error
+	// Status 返回工作区中有修改和未跟踪的文件以及 stash 数量
+	Status(ctx context.Context, path string) (GitStatus, error)
+	// Stash 将已跟踪文件的修改保存到 stash，未跟踪文件保留在工作区
+	Stash(ctx context.Context, path, message string) error
+	// StashPop 恢复最近一次 stash 的修改，恢复失败时 stash 保留
+	StashPop(ctx context.Context, path string) error
+	// DiscardChanges 丢弃已跟踪文件的修改（等同于 git reset --hard HEAD），未跟踪文件保留
+	DiscardChanges(ctx context.Context, path string) error
+}
+
+// gitBackends 可用的 Git 后端，测试时可以替换为假实现
+var gitBackends = map[string]GitBackend{
+	models.GitBackendExec:  execBackend{},
+	models.GitBackendGoGit: goGitBackend{},
+}
+
+// gitBackendFor 返回项目使用的 Git 后端，未配置时使用 exec
+func gitBackendFor(project models.Project) GitBackend {
+	if backend, ok := gitBackends[project.GitBackend]; ok {
+		return backend
+	}
+	return gitBackends[models.GitBackendExec]
+}
+
+// shortHash 返回 7 位短提交哈希
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// checkRefName 按 git check-ref-format 的规则检查用户提供的标签、分支或提交名称
+// 以 - 开头的名称会被 git 当作选项（如 -f、--orphan=x），一律拒绝
+func checkRefName(ref string) error {
+	switch {
+	case ref == "":
+		return fmt.Errorf("名称不能为空")
+	case strings.HasPrefix(ref, "-"):
+		return fmt.Errorf("名称不能以 - 开头")
+	case strings.HasPrefix(ref, "/") || strings.HasSuffix(ref, "/") || strings.HasSuffix(ref, ".") || strings.HasSuffix(ref, ".lock"):
+		return fmt.Errorf("名称不能以 / 开头，不能以 /、. 或 .lock 结尾")
+	case strings.Contains(ref, "..") || strings.Contains(ref, "//") || strings.Contains(ref, "@{") || strings.Contains(ref, "/."):
+		return fmt.Errorf("名称不能包含 ..、//、@{ 或以 . 开头的部分")
+	case strings.HasPrefix(ref, "."):
+		return fmt.Errorf("名称不能以 . 开头")
+	}
+	for _, r := range ref {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) {
+			return fmt.Errorf("名称不能包含空白、控制字符或 ~^:?*[\\")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckRefName(t *testing.T) {
+	tests := []struct {
+		ref   string
+		valid bool
+	}{
+		{"v1.2.0", true},
+		{"main", true},
+		{"origin/feature/login", true},
+		{"release-2024", true},
+		{"", false},
+		{"-f", false},
+		{"--orphan=x", false},
+		{"a..b", false},
+		{"main@{1}", false},
+		{"has space", false},
+		{"HEAD~1", false},
+		{"v1^", false},
+		{"a:b", false},
+		{"feature/", false},
+		{"feature/.hidden", false},
+		{".hidden", false},
+		{"topic.lock", false},
+		{"tab\tname", false},
+	}
+	for _, tt := range tests {
+		if err := checkRefName(tt.ref); (err == nil) != tt.valid {
+			t.Errorf("checkRefName(%q) = %v，期望有效: %v", tt.ref, err, tt.valid)
+		}
+	}
+}
+
+// newTestRepo 创建带有标签 v1 的本地仓库
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("未安装 git")
+	}
+	dir := t.TempDir()
+	runTestGit(t, dir, "init", "--initial-branch=main")
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, dir, "add", "README")
+	runTestGit(t, dir, "commit", "-m", "v1")
+	runTestGit(t, dir, "tag", "v1")
+	return dir
+}
+
+func TestExecBackendRejectsOptionLikeRefs(t *testing.T) {
+	dir := newTestRepo(t)
+	ctx := context.Background()
+	backend := execBackend{}
+
+	if err := backend.CheckoutTag(ctx, dir, "--orphan=x"); err == nil {
+		t.Error("以 - 开头的标签名应被拒绝")
+	}
+	if err := backend.CheckoutBranch(ctx, dir, "-f"); err == nil {
+		t.Error("以 - 开头的分支名应被拒绝")
+	}
+	if err := backend.CheckoutCommit(ctx, dir, "--orphan=x"); err == nil {
+		t.Error("以 - 开头的提交应被拒绝")
+	}
+
+	head, err := backend.Head(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Branch != "main" {
+		t.Errorf("拒绝的检出不应改变 HEAD，当前分支为 %q", head.Branch)
+	}
+}
+
+func TestExecBackendCheckoutTagAndCommit(t *testing.T) {
+	dir := newTestRepo(t)
+	ctx := context.Background()
+	backend := execBackend{}
+	// 与标签同名的分支不应影响检出标签
+	runTestGit(t, dir, "branch", "v1")
+
+	if err := backend.CheckoutTag(ctx, dir, "v1"); err != nil {
+		t.Fatalf("检出标签失败: %v", err)
+	}
+	head, err := backend.Head(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Branch != "" || head.Tag != "v1" {
+		t.Errorf("应处于标签 v1 的游离状态，实际分支 %q、标签 %q", head.Branch, head.Tag)
+	}
+
+	if err := backend.CheckoutCommit(ctx, dir, shortHash(head.Commit)); err != nil {
+		t.Fatalf("按短哈希检出提交失败: %v", err)
+	}
+	if after, _ := backend.Head(ctx, dir); after.Commit != head.Commit {
+		t.Errorf("检出的提交为 %s，期望 %s", after.Commit, head.Commit)
+	}
+}
@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// execBackend 调用系统 git 命令的后端，自动处理 dubious ownership 权限问题
+type execBackend struct{}
+
+// git 执行 git 命令
+func (execBackend) git(ctx context.Context, path string, args ...string) (string, error) {
+	return (&VersionController{}).executeGitCommand(ctx, path, args...)
+}
+
+// Fetch 从所有远程仓库获取分支和标签
+func (b execBackend) Fetch(ctx context.Context, path string) error {
+	_, err := b.git(ctx, path, "fetch", "--all", "--tags")
+	return err
+}
+
+// for-each-ref 输出中的字段分隔符和记录分隔符，标签备注可能包含换行，不能按行解析
+const (
+	refFieldSep  = "\x1f"
+	refRecordSep = "\x1e"
+)
+
+// forEachRef 用一次 git for-each-ref 获取引用及其详情，format 中的字段用 %1f 分隔
+// 返回每个引用的字段，字段数不等于 fields 的记录被跳过
+func (b execBackend) forEachRef(ctx context.Context, path string, fields int, format string, args ...string) ([][]string, error) {
+	args = append([]string{"for-each-ref", "--format=" + format + "%1e"}, args...)
+	output, err := b.git(ctx, path, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var records [][]string
+	for _, record := range strings.Split(output, refRecordSep) {
+		parts := strings.Split(strings.TrimLeft(record, "\n"), refFieldSep)
+		if len(parts) != fields {
+			continue
+		}
+		records = append(records, parts)
+	}
+	return records, nil
+}
+
+// parseRefTime 解析 for-each-ref 输出的 ISO 8601 时间，解析失败时返回零值
+func parseRefTime(value string) time.Time {
+	t, _ := time.Parse(time.RFC3339, value)
+	return t
+}
+
+// Tags 按版本号降序返回标签
+// 时间为附注标签打标签的时间，轻量标签为指向的提交的时间
+func (b execBackend) Tags(ctx context.Context, path string) ([]GitRef, error) {
+	// *objectname 为附注标签指向的提交，轻量标签为空
+	records, err := b.forEachRef(ctx, path, 5,
+		"%(refname:strip=2)%1f%(objectname)%1f%(*objectname)%1f%(creatordate:iso-strict)%1f%(contents)",
+		"--sort=-version:refname", "refs/tags")
+	if err != nil {
+		return nil, fmt.Errorf("获取标签列表失败: %v", err)
+	}
+
+	refs := make([]GitRef, 0, len(records))
+	for _, fields := range records {
+		ref := GitRef{
+			Name:    fields[0],
+			Commit:  fields[1],
+			Time:    parseRefTime(fields[3]),
+			Message: strings.TrimSpace(fields[4]),
+		}
+		if fields[2] != "" {
+			ref.Commit = fields[2]
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// Branches 返回本地分支和远程分支，本地分支在前，各自按名称排序
+func (b execBackend) Branches(ctx context.Context, path string) ([]GitRef, error) {
+	records, err := b.forEachRef(ctx, path, 5,
+		"%(refname)%1f%(symref)%1f%(objectname)%1f%(committerdate:iso-strict)%1f%(contents:subject)",
+		"refs/heads", "refs/remotes")
+	if err != nil {
+		return nil, fmt.Errorf("获取分支列表失败: %v", err)
+	}
+
+	refs := make([]GitRef, 0, len(records))
+	for _, fields := range records {
+		// 跳过 origin/HEAD 等符号引用
+		if fields[1] != "" {
+			continue
+		}
+		ref := GitRef{
+			Commit:  fields[2],
+			Time:    parseRefTime(fields[3]),
+			Message: fields[4],
+		}
+		if name, ok := strings.CutPrefix(fields[0], "refs/remotes/"); ok {
+			// 保留 origin/ 等远程名前缀
+			ref.Name = name
+			ref.Remote = true
+		} else {
+			ref.Name = strings.TrimPrefix(fields[0], "refs/heads/")
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// Head 返回当前 HEAD 的状态
+func (b execBackend) Head(ctx context.Context, path string) (GitHead, error) {
+	commit, err := b.git(ctx, path, "rev-parse", "HEAD")
+	if err != nil {
+		return GitHead{}, fmt.Errorf("获取当前提交失败: %v", err)
+	}
+	head := GitHead{Commit: commit}
+
+	if branch, err := b.git(ctx, path, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" && branch != "" {
+		head.Branch = branch
+		return head, nil
+	}
+	if tag, err := b.git(ctx, path, "describe", "--exact-match", "--tags"); err == nil && tag != "" {
+		head.Tag = tag
+		return head, nil
+	}
+	if describe, err := b.git(ctx, path, "describe", "--tags"); err == nil {
+		head.Describe = describe
+	}
+	return head, nil
+}
+
+// resolveCommit 将名称解析为完整提交哈希，--end-of-options 避免名称被当作选项
+func (b execBackend) resolveCommit(ctx context.Context, path, rev string) (string, error) {
+	commit, err := b.git(ctx, path, "rev-parse", "--verify", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("找不到提交 %s: %v", rev, err)
+	}
+	return commit, nil
+}
+
+// CheckoutTag 检出标签，使用完整引用名，标签名不会被当作选项或同名分支
+func (b execBackend) CheckoutTag(ctx context.Context, path, tag string) error {
+	if err := checkRefName(tag); err != nil {
+		return fmt.Errorf("标签名 %s 无效: %v", tag, err)
+	}
+	_, err := b.git(ctx, path, "checkout", "refs/tags/"+tag)
+	return err
+}
+
+// CheckoutBranch 切换到分支并拉取更新，本地不存在时从 origin 创建跟踪分支
+func (b execBackend) CheckoutBranch(ctx context.Context, path, branch string) error {
+	if err := checkRefName(branch); err != nil {
+		return fmt.Errorf("分支名 %s 无效: %v", branch, err)
+	}
+
+	// 处理远程分支名称
+	localBranch := strings.TrimPrefix(branch, "origin/")
+
+	// 检查本地分支是否存在
+	if _, err := b.git(ctx, path, "show-ref", "--verify", "--quiet", "refs/heads/"+localBranch); err != nil {
+		// 本地分支不存在，创建并跟踪远程分支
+		if _, err := b.git(ctx, path, "checkout", "-b", localBranch, "origin/"+localBranch); err != nil {
+			return fmt.Errorf("创建并检出分支 %s 失败: %v", localBranch, err)
+		}
+		return nil
+	}
+
+	// 本地分支存在，直接切换
+	if _, err := b.git(ctx, path, "checkout", localBranch); err != nil {
+		return fmt.Errorf("切换到分支 %s 失败: %v", localBranch, err)
+	}
+
+	// 更新本地分支到最新
+	if _, err := b.git(ctx, path, "pull", "origin", localBranch); err != nil {
+		// pull 失败不是致命错误，只是记录警告
+		fmt.Printf("⚠️ 更新分支 %s 失败: %v\n", localBranch, err)
+	}
+	return nil
+}
+
+// CheckoutCommit 检出提交，先解析为完整哈希再检出
+func (b execBackend) CheckoutCommit(ctx context.Context, path, commit string) error {
+	hash, err := b.resolveCommit(ctx, path, commit)
+	if err != nil {
+		return err
+	}
+	_, err = b.git(ctx, path, "checkout", hash)
+	return err
+}
+
+// ResetBranch 切换到分支并将其移动到指定提交
+func (b execBackend) ResetBranch(ctx context.Context, path, branch, commit string) error {
+	if err := checkRefName(branch); err != nil {
+		return fmt.Errorf("分支名 %s 无效: %v", branch, err)
+	}
+	commit, err := b.resolveCommit(ctx, path, commit)
+	if err != nil {
+		return err
+	}
+
+	if _, err := b.git(ctx, path, "checkout", branch); err != nil {
+		return fmt.Errorf("切换到分支 %s 失败: %v", branch, err)
+	}
+
+	current, err := b.git(ctx, path, "rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Errorf("获取当前提交失败: %v", err)
+	}
+	if current != commit {
+		if _, err := b.git(ctx, path, "reset", "--keep", commit); err != nil {
+			return fmt.Errorf("将分支 %s 重置到 %s 失败: %v", branch, commit, err)
+		}
+	}
+	return nil
+}
+
+// Status 解析 git status --porcelain=v2 的输出
+func (b execBackend) Status(ctx context.Context, path string) (GitStatus, error) {
+	var status GitStatus
+	output, err := b.git(ctx, path, "status", "--porcelain=v2", "--untracked-files=all")
+	if err != nil {
+		return status, fmt.Errorf("获取工作区状态失败: %v", err)
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "? "):
+			status.Untracked = append(status.Untracked, strings.TrimPrefix(line, "? "))
+		case strings.HasPrefix(line, "1 "):
+			// 1 XY sub mH mI mW hH hI path
+			if fields := strings.SplitN(line, " ", 9); len(fields) == 9 {
+				status.Modified = append(status.Modified, fields[8])
+			}
+		case strings.HasPrefix(line, "2 "):
+			// 2 XY sub mH mI mW hH hI Xscore path<tab>origPath
+			if fields := strings.SplitN(line, " ", 10); len(fields) == 10 {
+				newPath, _, _ := strings.Cut(fields[9], "\t")
+				status.Modified = append(status.Modified, newPath)
+			}
+		case strings.HasPrefix(line, "u "):
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			if fields := strings.SplitN(line, " ", 11); len(fields) == 11 {
+				status.Modified = append(status.Modified, fields[10])
+			}
+		}
+	}
+
+	if stashes, err := b.git(ctx, path, "stash", "list"); err == nil && stashes != "" {
+		status.Stashes = len(strings.Split(stashes, "\n"))
+	}
+	return status, nil
+}
+
+// Stash 将已跟踪文件的修改保存到 stash，服务器上可能没有配置 Git 用户，使用 gover 作为提交者
+func (b execBackend) Stash(ctx context.Context, path, message string) error {
+	_, err := b.git(ctx, path, "-c", "user.name=gover", "-c", "user.email=gover@localhost", "stash", "push", "-m", message)
+	return err
+}
+
+// StashPop 恢复最近一次 stash 的修改
+func (b execBackend) StashPop(ctx context.Context, path string) error {
+	if _, err := b.git(ctx, path, "stash", "pop"); err != nil {
+		return fmt.Errorf("本地修改无法应用到当前版本（可能存在冲突）: %v", err)
+	}
+	return nil
+}
+
+// DiscardChanges 丢弃已跟踪文件的修改
+func (b execBackend) DiscardChanges(ctx context.Context, path string) error {
+	_, err := b.git(ctx, path, "reset", "--hard", "HEAD")
+	return err
+}
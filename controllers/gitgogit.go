@@ -0,0 +1,393 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// goGitBackend 基于 go-git 的纯 Go 后端，不依赖系统中的 git 命令
+// SSH 远程仓库通过 ssh-agent 认证，HTTPS 远程仓库只支持匿名访问
+type goGitBackend struct{}
+
+// open 打开项目仓库
+func (goGitBackend) open(path string) (*git.Repository, error) {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开仓库 %s 失败: %v", path, err)
+	}
+	return repo, nil
+}
+
+// Fetch 从所有远程仓库获取分支和标签
+func (b goGitBackend) Fetch(ctx context.Context, path string) error {
+	repo, err := b.open(path)
+	if err != nil {
+		return err
+	}
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return fmt.Errorf("读取远程仓库配置失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, GitNetworkTimeout)
+	defer cancel()
+
+	for _, remote := range remotes {
+		err := remote.FetchContext(ctx, &git.FetchOptions{Tags: git.AllTags})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("fetch %s 超时 (%s): %w", remote.Config().Name, GitNetworkTimeout, ctx.Err())
+			}
+			return fmt.Errorf("fetch %s 失败: %v", remote.Config().Name, err)
+		}
+	}
+	return nil
+}
+
+// Tags 按版本号降序返回标签
+// 时间为附注标签打标签的时间，轻量标签为指向的提交的时间
+func (b goGitBackend) Tags(ctx context.Context, path string) ([]GitRef, error) {
+	repo, err := b.open(path)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("获取标签列表失败: %v", err)
+	}
+
+	var refs []GitRef
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		tag := GitRef{Name: ref.Name().Short()}
+		commit, message, err := b.peelTag(repo, ref)
+		if err != nil {
+			if DebugMode {
+				fmt.Printf("⚠️ 获取标签 %s 详情失败: %v\n", tag.Name, err)
+			}
+			refs = append(refs, tag)
+			return nil
+		}
+		tag.Commit = commit.Hash.String()
+		tag.Time = commit.Committer.When
+		if annotated, err := repo.TagObject(ref.Hash()); err == nil {
+			tag.Time = annotated.Tagger.When
+		}
+		tag.Message = message
+		refs = append(refs, tag)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取标签列表失败: %v", err)
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		if cmp := compareVersions(refs[i].Name, refs[j].Name); cmp != 0 {
+			return cmp > 0
+		}
+		return refs[i].Name > refs[j].Name
+	})
+	return refs, nil
+}
+
+// peelTag 返回标签指向的提交和标签备注，轻量标签的备注为提交说明
+func (goGitBackend) peelTag(repo *git.Repository, ref *plumbing.Reference) (*object.Commit, string, error) {
+	if tag, err := repo.TagObject(ref.Hash()); err == nil {
+		commit, err := tag.Commit()
+		if err != nil {
+			return nil, "", err
+		}
+		return commit, strings.TrimSpace(tag.Message), nil
+	}
+
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, "", err
+	}
+	return commit, strings.TrimSpace(commit.Message), nil
+}
+
+// Branches 返回本地分支和远程分支，本地分支在前，各自按名称排序
+func (b goGitBackend) Branches(ctx context.Context, path string) ([]GitRef, error) {
+	repo, err := b.open(path)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("获取分支列表失败: %v", err)
+	}
+
+	var refs []GitRef
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		// 跳过标签和 origin/HEAD 等符号引用
+		if ref.Type() != plumbing.HashReference || !(ref.Name().IsBranch() || ref.Name().IsRemote()) {
+			return nil
+		}
+		branch := GitRef{
+			Name:   ref.Name().Short(),
+			Commit: ref.Hash().String(),
+			Remote: ref.Name().IsRemote(),
+		}
+		if commit, err := repo.CommitObject(ref.Hash()); err == nil {
+			branch.Time = commit.Committer.When
+			branch.Message, _, _ = strings.Cut(strings.TrimSpace(commit.Message), "\n")
+		}
+		refs = append(refs, branch)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取分支列表失败: %v", err)
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Remote != refs[j].Remote {
+			return !refs[i].Remote
+		}
+		return refs[i].Name < refs[j].Name
+	})
+	return refs, nil
+}
+
+// Head 返回当前 HEAD 的状态
+func (b goGitBackend) Head(ctx context.Context, path string) (GitHead, error) {
+	repo, err := b.open(path)
+	if err != nil {
+		return GitHead{}, err
+	}
+	ref, err := repo.Head()
+	if err != nil {
+		return GitHead{}, fmt.Errorf("获取当前提交失败: %v", err)
+	}
+	head := GitHead{Commit: ref.Hash().String()}
+	if ref.Name().IsBranch() {
+		head.Branch = ref.Name().Short()
+		return head, nil
+	}
+
+	tags, err := b.tagsByCommit(repo)
+	if err != nil || len(tags) == 0 {
+		return head, nil
+	}
+	if names, ok := tags[ref.Hash()]; ok {
+		head.Tag = names[0]
+		return head, nil
+	}
+	head.Describe = b.describe(repo, ref.Hash(), tags)
+	return head, nil
+}
+
+// tagsByCommit 返回每个提交上的标签，同一提交上有多个标签时版本号最大的在前
+func (b goGitBackend) tagsByCommit(repo *git.Repository) (map[plumbing.Hash][]string, error) {
+	iter, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[plumbing.Hash][]string)
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if commit, _, err := b.peelTag(repo, ref); err == nil {
+			tags[commit.Hash] = append(tags[commit.Hash], ref.Name().Short())
+		}
+		return nil
+	})
+	for _, names := range tags {
+		sort.Slice(names, func(i, j int) bool {
+			return compareVersions(names[i], names[j]) > 0
+		})
+	}
+	return tags, err
+}
+
+// describe 沿提交历史查找最近的标签，返回与 git describe --tags 相同格式的描述，找不到时返回空
+func (goGitBackend) describe(repo *git.Repository, from plumbing.Hash, tags map[plumbing.Hash][]string) string {
+	iter, err := repo.Log(&git.LogOptions{From: from})
+	if err != nil {
+		return ""
+	}
+	defer iter.Close()
+
+	describe := ""
+	depth := 0
+	_ = iter.ForEach(func(commit *object.Commit) error {
+		if names, ok := tags[commit.Hash]; ok {
+			describe = fmt.Sprintf("%s-%d-g%s", names[0], depth, shortHash(from.String()))
+			return storer.ErrStop
+		}
+		depth++
+		return nil
+	})
+	return describe
+}
+
+// worktree 打开项目仓库及其工作区
+func (b goGitBackend) worktree(path string) (*git.Repository, *git.Worktree, error) {
+	repo, err := b.open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, nil, fmt.Errorf("打开工作区失败: %v", err)
+	}
+	return repo, wt, nil
+}
+
+// CheckoutTag 检出标签
+func (b goGitBackend) CheckoutTag(ctx context.Context, path, tag string) error {
+	repo, wt, err := b.worktree(path)
+	if err != nil {
+		return err
+	}
+	ref, err := repo.Tag(tag)
+	if err != nil {
+		return fmt.Errorf("标签 %s 不存在: %v", tag, err)
+	}
+	commit, _, err := b.peelTag(repo, ref)
+	if err != nil {
+		return fmt.Errorf("解析标签 %s 失败: %v", tag, err)
+	}
+	return wt.Checkout(&git.CheckoutOptions{Hash: commit.Hash})
+}
+
+// CheckoutBranch 切换到分支并拉取更新，本地不存在时从 origin 创建跟踪分支
+func (b goGitBackend) CheckoutBranch(ctx context.Context, path, branch string) error {
+	repo, wt, err := b.worktree(path)
+	if err != nil {
+		return err
+	}
+
+	// 处理远程分支名称
+	localBranch := strings.TrimPrefix(branch, "origin/")
+	branchRef := plumbing.NewBranchReferenceName(localBranch)
+
+	// 本地分支不存在，创建并跟踪远程分支
+	if _, err := repo.Reference(branchRef, false); err != nil {
+		remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", localBranch), true)
+		if err != nil {
+			return fmt.Errorf("创建并检出分支 %s 失败: 远程分支 origin/%s 不存在", localBranch, localBranch)
+		}
+		if err := wt.Checkout(&git.CheckoutOptions{Branch: branchRef, Hash: remoteRef.Hash(), Create: true}); err != nil {
+			return fmt.Errorf("创建并检出分支 %s 失败: %v", localBranch, err)
+		}
+		err = repo.CreateBranch(&config.Branch{Name: localBranch, Remote: "origin", Merge: branchRef})
+		if err != nil && !errors.Is(err, git.ErrBranchExists) {
+			fmt.Printf("⚠️ 设置分支 %s 的上游分支失败: %v\n", localBranch, err)
+		}
+		return nil
+	}
+
+	// 本地分支存在，直接切换
+	if err := wt.Checkout(&git.CheckoutOptions{Branch: branchRef}); err != nil {
+		return fmt.Errorf("切换到分支 %s 失败: %v", localBranch, err)
+	}
+
+	// 更新本地分支到最新（只支持快进）
+	pullCtx, cancel := context.WithTimeout(ctx, GitNetworkTimeout)
+	defer cancel()
+	err = wt.PullContext(pullCtx, &git.PullOptions{RemoteName: "origin", ReferenceName: branchRef, SingleBranch: true})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		// pull 失败不是致命错误，只是记录警告
+		fmt.Printf("⚠️ 更新分支 %s 失败: %v\n", localBranch, err)
+	}
+	return nil
+}
+
+// CheckoutCommit 检出提交
+func (b goGitBackend) CheckoutCommit(ctx context.Context, path, commit string) error {
+	_, wt, err := b.worktree(path)
+	if err != nil {
+		return err
+	}
+	return wt.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(commit)})
+}
+
+// ResetBranch 切换到分支并将其移动到指定提交
+func (b goGitBackend) ResetBranch(ctx context.Context, path, branch, commit string) error {
+	repo, wt, err := b.worktree(path)
+	if err != nil {
+		return err
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch)}); err != nil {
+		return fmt.Errorf("切换到分支 %s 失败: %v", branch, err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("获取当前提交失败: %v", err)
+	}
+	if head.Hash().String() != commit {
+		if err := wt.Reset(&git.ResetOptions{Commit: plumbing.NewHash(commit), Mode: git.MergeReset}); err != nil {
+			return fmt.Errorf("将分支 %s 重置到 %s 失败: %v", branch, commit, err)
+		}
+	}
+	return nil
+}
+
+// errGoGitStash go-git 不支持 stash
+var errGoGitStash = errors.New("go-git 后端不支持 stash，请使用 exec 后端")
+
+// Status 返回工作区状态，stash 数量从 stash 的引用日志中读取
+func (b goGitBackend) Status(ctx context.Context, path string) (GitStatus, error) {
+	var status GitStatus
+	_, wt, err := b.worktree(path)
+	if err != nil {
+		return status, err
+	}
+	files, err := wt.Status()
+	if err != nil {
+		return status, fmt.Errorf("获取工作区状态失败: %v", err)
+	}
+
+	for file, fileStatus := range files {
+		switch {
+		case fileStatus.Worktree == git.Untracked:
+			status.Untracked = append(status.Untracked, file)
+		case fileStatus.Worktree != git.Unmodified || fileStatus.Staging != git.Unmodified:
+			status.Modified = append(status.Modified, file)
+		}
+	}
+	sort.Strings(status.Modified)
+	sort.Strings(status.Untracked)
+
+	if data, err := os.ReadFile(filepath.Join(projectGitDir(path), "logs", "refs", "stash")); err == nil {
+		status.Stashes = len(strings.Split(strings.TrimSpace(string(data)), "\n"))
+	}
+	return status, nil
+}
+
+// Stash go-git 不支持
+func (goGitBackend) Stash(ctx context.Context, path, message string) error {
+	return errGoGitStash
+}
+
+// StashPop go-git 不支持
+func (goGitBackend) StashPop(ctx context.Context, path string) error {
+	return errGoGitStash
+}
+
+// DiscardChanges 丢弃已跟踪文件的修改，只恢复有修改的文件（go-git 的 HardReset 会删除未跟踪的文件）
+func (b goGitBackend) DiscardChanges(ctx context.Context, path string) error {
+	status, err := b.Status(ctx, path)
+	if err != nil {
+		return err
+	}
+	if len(status.Modified) == 0 {
+		return nil
+	}
+
+	_, wt, err := b.worktree(path)
+	if err != nil {
+		return err
+	}
+	return wt.Restore(&git.RestoreOptions{Staged: true, Worktree: true, Files: status.Modified})
+}
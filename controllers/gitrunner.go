@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Git 命令超时配置
+var (
+	GitCommandTimeout = 30 * time.Second // 本地命令（rev-parse、tag、checkout 等）的超时时间
+	GitNetworkTimeout = 2 * time.Minute  // 访问远程仓库的命令（fetch、pull 等）的超时时间
+
+	GitListFetchTimeout = 10 * time.Second // 刷新标签/分支列表前 fetch 的最长等待时间，超时后使用本地数据
+)
+
+// gitNetworkCommands 需要访问远程仓库的子命令
+var gitNetworkCommands = map[string]bool{
+	"fetch":     true,
+	"pull":      true,
+	"push":      true,
+	"clone":     true,
+	"ls-remote": true,
+}
+
+// gitSubcommand 返回参数中的子命令，跳过 -c key=value 等全局选项
+func gitSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-c" || args[i] == "-C":
+			i++
+		case !strings.HasPrefix(args[i], "-"):
+			return args[i]
+		}
+	}
+	return ""
+}
+
+// gitTimeout 返回命令的超时时间
+func gitTimeout(args []string) time.Duration {
+	if gitNetworkCommands[gitSubcommand(args)] {
+		return GitNetworkTimeout
+	}
+	return GitCommandTimeout
+}
+
+// gitCommand 创建在 dir 中执行的 git 命令，ctx 取消时终止整个进程组（包括 ssh、credential helper 等子进程）
+func gitCommand(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = time.Second // 子进程可能仍持有输出管道，避免终止后无限等待
+	return cmd
+}
+
+// runGit 执行 git 命令并返回去掉首尾空白的标准输出，超过命令对应的超时时间或 ctx 取消时终止命令
+// env 为空时继承当前进程的环境变量
+func runGit(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	timeout := gitTimeout(args)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := gitCommand(ctx, dir, args...)
+	cmd.Env = env
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return "", fmt.Errorf("git %s 超时 (%s): %w", gitSubcommand(args), timeout, ctx.Err())
+		case errors.Is(ctx.Err(), context.Canceled):
+			return "", fmt.Errorf("git %s 已取消: %w", gitSubcommand(args), ctx.Err())
+		}
+		output := strings.TrimSpace(stderr.String())
+		// 部分命令（如 stash pop、merge 冲突）把失败原因写到标准输出
+		if stdout := strings.TrimSpace(out.String()); stdout != "" {
+			output = strings.TrimSpace(output + "\n" + stdout)
+		}
+		return "", fmt.Errorf("命令执行失败: %v, 输出: %s", err, output)
+	}
+
+	return strings.TrimSpace(out.String()), nil
+}
+
+// requestContext 返回当前 HTTP 请求的 context，客户端断开时取消正在执行的 git 命令
+func (c *VersionController) requestContext() context.Context {
+	if c.Ctx != nil && c.Ctx.Request != nil {
+		return c.Ctx.Request.Context()
+	}
+	return context.Background()
+}
+
+// deployContext 返回部署使用的 context：部署开始后不随客户端断开而中止，避免仓库停在检出或回滚的中间状态
+// 每条 git 命令仍受各自的超时时间限制
+func (c *VersionController) deployContext() context.Context {
+	return context.WithoutCancel(c.requestContext())
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"fmt"
+	"gover/models"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 健康检查默认值
+const (
+	defaultHealthStatus   = http.StatusOK
+	defaultHealthRetries  = 5
+	defaultHealthInterval = 3 * time.Second
+	defaultHealthTimeout  = 5 * time.Second
+	maxHealthBody         = 1024 * 1024 // 读取响应体的最大字节数
+)
+
+// HealthCheckResult 健康检查结果
+type HealthCheckResult struct {
+	URL        string        `json:"url"`
+	Healthy    bool          `json:"healthy"`
+	Attempts   int           `json:"attempts"`
+	StatusCode int           `json:"status_code"`
+	Error      string        `json:"error,omitempty"` // 最后一次失败的原因
+	Duration   time.Duration `json:"duration"`
+}
+
+// Summary 返回用于页面展示的简短描述
+func (r *HealthCheckResult) Summary() string {
+	if r.Healthy {
+		return fmt.Sprintf("健康检查通过 (HTTP %d，第 %d 次尝试)", r.StatusCode, r.Attempts)
+	}
+	return fmt.Sprintf("健康检查失败 (已尝试 %d 次): %s", r.Attempts, r.Error)
+}
+
+// runHealthCheck 轮询健康检查地址，直到成功或用完重试次数
+func runHealthCheck(hc *models.HealthCheck) *HealthCheckResult {
+	result := &HealthCheckResult{URL: hc.URL}
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	expectedStatus := hc.ExpectedStatus
+	if expectedStatus == 0 {
+		expectedStatus = defaultHealthStatus
+	}
+	retries := hc.Retries
+	if retries <= 0 {
+		retries = defaultHealthRetries
+	}
+	interval := defaultHealthInterval
+	if hc.Interval > 0 {
+		interval = time.Duration(hc.Interval) * time.Second
+	}
+	timeout := defaultHealthTimeout
+	if hc.Timeout > 0 {
+		timeout = time.Duration(hc.Timeout) * time.Second
+	}
+
+	client := &http.Client{Timeout: timeout}
+
+	for attempt := 1; attempt <= retries; attempt++ {
+		result.Attempts = attempt
+
+		statusCode, err := probeHealth(client, hc.URL, expectedStatus, hc.BodyContains)
+		result.StatusCode = statusCode
+		if err == nil {
+			result.Healthy = true
+			result.Error = ""
+			return result
+		}
+		result.Error = err.Error()
+
+		if DebugMode {
+			fmt.Printf("🩺 健康检查 %s 第 %d/%d 次失败: %v\n", hc.URL, attempt, retries, err)
+		}
+
+		if attempt < retries {
+			time.Sleep(interval)
+		}
+	}
+
+	return result
+}
+
+// probeHealth 执行一次健康检查请求
+func probeHealth(client *http.Client, url string, expectedStatus int, bodyContains string) (int, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHealthBody))
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	if resp.StatusCode != expectedStatus {
+		return resp.StatusCode, fmt.Errorf("状态码 %d，期望 %d", resp.StatusCode, expectedStatus)
+	}
+
+	if bodyContains != "" && !strings.Contains(string(body), bodyContains) {
+		return resp.StatusCode, fmt.Errorf("响应内容不包含 %q", bodyContains)
+	}
+
+	return resp.StatusCode, nil
+}
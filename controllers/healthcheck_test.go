@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gover/models"
+)
+
+func TestRunHealthCheckHealthy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("status: ok"))
+	}))
+	defer server.Close()
+
+	result := runHealthCheck(&models.HealthCheck{URL: server.URL, BodyContains: "ok", Retries: 1})
+	if !result.Healthy {
+		t.Fatalf("期望健康检查通过，实际失败: %s", result.Error)
+	}
+	if result.StatusCode != http.StatusOK || result.Attempts != 1 {
+		t.Errorf("状态码 %d、尝试 %d 次，期望 200、1 次", result.StatusCode, result.Attempts)
+	}
+}
+
+func TestRunHealthCheckWrongStatus(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	result := runHealthCheck(&models.HealthCheck{URL: server.URL, Retries: 1})
+	if result.Healthy {
+		t.Fatal("状态码 503 时健康检查不应通过")
+	}
+	if result.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("状态码 %d，期望 503", result.StatusCode)
+	}
+	if !strings.Contains(result.Error, "状态码 503") {
+		t.Errorf("错误信息 %q 没有说明状态码", result.Error)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("请求了 %d 次，期望 1 次", got)
+	}
+}
+
+func TestRunHealthCheckBodyMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("starting"))
+	}))
+	defer server.Close()
+
+	result := runHealthCheck(&models.HealthCheck{URL: server.URL, BodyContains: "ok", Retries: 1})
+	if result.Healthy {
+		t.Fatal("响应内容不匹配时健康检查不应通过")
+	}
+	if result.StatusCode != http.StatusOK {
+		t.Errorf("状态码 %d，期望 200", result.StatusCode)
+	}
+}
+
+func TestRunHealthCheckRetriesUntilHealthy(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	result := runHealthCheck(&models.HealthCheck{URL: server.URL, Retries: 3, Interval: 1})
+	if !result.Healthy {
+		t.Fatalf("期望第二次尝试通过，实际失败: %s", result.Error)
+	}
+	if result.Attempts != 2 {
+		t.Errorf("尝试了 %d 次，期望 2 次", result.Attempts)
+	}
+}
+
+func TestRunHealthCheckTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	result := runHealthCheck(&models.HealthCheck{URL: server.URL, Retries: 1, Timeout: 1})
+	if result.Healthy {
+		t.Fatal("请求超时时健康检查不应通过")
+	}
+	if result.StatusCode != 0 {
+		t.Errorf("超时时状态码为 %d，期望 0", result.StatusCode)
+	}
+	if !strings.Contains(result.Error, "请求失败") {
+		t.Errorf("错误信息 %q 没有说明请求失败", result.Error)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("超时设置为 1 秒，实际等待了 %s", elapsed)
+	}
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"gover/models"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// HistoryController 部署历史控制器
+type HistoryController struct {
+	web.Controller
+}
+
+// historyFilter 从请求参数构造查询条件
+func (c *HistoryController) historyFilter(defaultLimit int) models.HistoryFilter {
+	limit, err := c.GetInt("limit", defaultLimit)
+	if err != nil || limit < 0 {
+		limit = defaultLimit
+	}
+	return models.HistoryFilter{
+		Project: c.GetString("project"),
+		User:    c.GetString("user"),
+		Result:  c.GetString("result"),
+		Limit:   limit,
+	}
+}
+
+// Index 显示部署历史页面
+func (c *HistoryController) Index() {
+	// 检查认证
+	identity := RequireAuth(&c.Controller)
+	if identity == nil {
+		return
+	}
+
+	filter := c.historyFilter(100)
+	limit := filter.Limit
+	filter.Limit = 0
+	entries, err := models.QueryHistory(filter)
+	if err != nil {
+		c.Data["Error"] = err.Error()
+	}
+	entries = filterHistoryForIdentity(identity, entries)
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	filter.Limit = limit
+
+	var projectNames []string
+	for _, project := range visibleProjects(identity) {
+		projectNames = append(projectNames, project.Name)
+	}
+
+	c.Data["Entries"] = entries
+	c.Data["Filter"] = filter
+	c.Data["ProjectNames"] = projectNames
+	c.Data["Title"] = "部署历史 - " + models.GetConfig().UI.Title
+	c.TplName = "version/history.html"
+}
+
+// List 以 JSON 格式返回部署历史，支持 project、user、result、limit 参数过滤
+func (c *HistoryController) List() {
+	// 检查认证（支持 API 令牌）
+	identity := requireJSONAuth(&c.Controller)
+	if identity == nil {
+		return
+	}
+
+	filter := c.historyFilter(50)
+	limit := filter.Limit
+	filter.Limit = 0
+	entries, err := models.QueryHistory(filter)
+	if err != nil {
+		c.Data["json"] = map[string]interface{}{
+			"success": false,
+			"message": err.Error(),
+		}
+		c.ServeJSON()
+		return
+	}
+
+	entries = filterHistoryForIdentity(identity, entries)
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	c.Data["json"] = map[string]interface{}{
+		"success": true,
+		"data":    entries,
+	}
+	c.ServeJSON()
+}
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gover/models"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 钩子步骤相关配置
+var (
+	DefaultHookTimeout = 5 * time.Minute // 步骤未配置超时时间时的默认值
+	maxHookOutput      = 64 * 1024       // 单个步骤保留的最大输出字节数
+)
+
+// HookResult 单个钩子步骤的执行结果
+type HookResult struct {
+	Phase    string        `json:"phase"`     // "pre"、"post" 或 "rollback"
+	Name     string        `json:"name"`      // 步骤名称
+	Command  string        `json:"command"`   // 执行的命令
+	Dir      string        `json:"dir"`       // 实际工作目录
+	Output   string        `json:"output"`    // 合并后的标准输出和标准错误
+	ExitCode int           `json:"exit_code"` // 退出码，-1 表示未能启动或被终止
+	Duration time.Duration `json:"duration"`  // 执行耗时
+	Error    string        `json:"error"`     // 错误信息，成功时为空
+}
+
+// Success 步骤是否执行成功
+func (r HookResult) Success() bool {
+	return r.Error == ""
+}
+
+// limitedBuffer 只保留前 limit 字节的输出，避免步骤输出过大占用内存
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remain := b.limit - b.buf.Len(); remain > 0 {
+		if len(p) > remain {
+			b.buf.Write(p[:remain])
+			b.truncated = true
+		} else {
+			b.buf.Write(p)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + "\n... (输出过长，已截断)"
+	}
+	return b.buf.String()
+}
+
+// hookStepName 返回步骤的显示名称
+func hookStepName(step models.HookStep, index int) string {
+	if step.Name != "" {
+		return step.Name
+	}
+	return fmt.Sprintf("步骤 %d", index+1)
+}
+
+// hookStepDir 计算步骤的工作目录
+func hookStepDir(project models.Project, step models.HookStep) string {
+	if step.Dir == "" {
+		return project.Path
+	}
+	if filepath.IsAbs(step.Dir) {
+		return step.Dir
+	}
+	return filepath.Join(project.Path, step.Dir)
+}
+
+// shellCommand 根据平台构造 Shell 命令
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// runHookStep 执行单个钩子步骤
+func runHookStep(project models.Project, phase string, index int, step models.HookStep, extraEnv map[string]string) HookResult {
+	result := HookResult{
+		Phase:   phase,
+		Name:    hookStepName(step, index),
+		Command: step.Command,
+		Dir:     hookStepDir(project, step),
+	}
+
+	if strings.TrimSpace(step.Command) == "" {
+		result.ExitCode = -1
+		result.Error = "命令不能为空"
+		return result
+	}
+
+	timeout := DefaultHookTimeout
+	if step.Timeout > 0 {
+		timeout = time.Duration(step.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, step.Command)
+	cmd.Dir = result.Dir
+	// 超时时终止整个进程组，避免构建等步骤启动的子进程继续运行
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = time.Second // 超时后子进程可能仍持有输出管道，避免无限等待
+
+	goverEnv := map[string]string{
+		"GOVER_PROJECT":      project.Name,
+		"GOVER_PROJECT_PATH": project.Path,
+		"GOVER_PHASE":        phase,
+	}
+	for key, value := range extraEnv {
+		goverEnv[key] = value
+	}
+	env := os.Environ()
+	for key, value := range goverEnv {
+		env = append(env, key+"="+value)
+	}
+	// 步骤的 env 在执行时展开，可以引用 gover 注入的变量，如 ${GOVER_TARGET_REF}
+	for key, value := range step.Env {
+		env = append(env, key+"="+os.Expand(value, func(name string) string {
+			if value, ok := goverEnv[name]; ok {
+				return value
+			}
+			return os.Getenv(name)
+		}))
+	}
+	cmd.Env = env
+
+	output := &limitedBuffer{limit: maxHookOutput}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration = time.Since(start)
+	result.Output = strings.TrimSpace(output.String())
+
+	if err == nil {
+		return result
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("执行超时 (%s)", timeout)
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Error = fmt.Sprintf("退出码 %d", result.ExitCode)
+	default:
+		result.ExitCode = -1
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// runHookSteps 按顺序执行钩子步骤，遇到失败立即停止
+// 返回已执行步骤的结果，以及第一个失败步骤对应的错误
+func runHookSteps(project models.Project, phase string, steps []models.HookStep, extraEnv map[string]string) ([]HookResult, error) {
+	var results []HookResult
+	for i, step := range steps {
+		if DebugMode {
+			fmt.Printf("🪝 [%s/%s] 执行 %s: %s\n", project.Name, phase, hookStepName(step, i), step.Command)
+		}
+
+		result := runHookStep(project, phase, i, step, extraEnv)
+		results = append(results, result)
+
+		if DebugMode && result.Output != "" {
+			fmt.Printf("%s\n", result.Output)
+		}
+
+		if !result.Success() {
+			fmt.Printf("❌ 项目 %s 的%s步骤 %s 失败: %s\n", project.Name, hookPhaseLabel(phase), result.Name, result.Error)
+			return results, fmt.Errorf("%s步骤 %s 失败: %s%s", hookPhaseLabel(phase), result.Name, result.Error, hookOutputTail(result.Output))
+		}
+	}
+	return results, nil
+}
+
+// hookPhaseLabel 返回阶段的中文描述
+func hookPhaseLabel(phase string) string {
+	switch phase {
+	case "pre":
+		return "检出前"
+	case "rollback":
+		return "回滚后"
+	default:
+		return "检出后"
+	}
+}
+
+// hookOutputTail 截取输出末尾几行，用于错误提示
+func hookOutputTail(output string) string {
+	if output == "" {
+		return ""
+	}
+	lines := strings.Split(output, "\n")
+	if len(lines) > 5 {
+		lines = lines[len(lines)-5:]
+	}
+	return "，输出: " + strings.Join(lines, " | ")
+}
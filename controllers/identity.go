@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"gover/models"
+	"net/http"
+	"strings"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// Identity 已认证的请求身份：登录用户或 API 令牌
+type Identity struct {
+	Name  string             // 用户名或令牌名称
+	User  *models.UserConfig // 通过登录会话认证时不为空
+	Token *models.APIToken   // 通过 API 令牌认证时不为空
+}
+
+// IsToken 是否通过 API 令牌认证
+func (i *Identity) IsToken() bool {
+	return i.Token != nil
+}
+
+// IsAdmin 是否为管理员（API 令牌不具备管理员权限）
+func (i *Identity) IsAdmin() bool {
+	return i.User != nil && i.User.IsAdmin()
+}
+
+// DisplayName 用于审计记录的身份名称
+func (i *Identity) DisplayName() string {
+	if i.IsToken() {
+		return "token:" + i.Name
+	}
+	return i.Name
+}
+
+// RoleFor 返回身份在指定项目上的角色，无权访问时返回空字符串
+func (i *Identity) RoleFor(project string) string {
+	switch {
+	case i.IsToken():
+		if !i.Token.AllowsProject(project) {
+			return ""
+		}
+		if i.Token.ReadOnly {
+			return models.RoleViewer
+		}
+		return models.RoleDeployer
+	case i.User != nil:
+		return i.User.RoleFor(project)
+	default:
+		return ""
+	}
+}
+
+// CanAccessProject 是否可以查看指定项目
+func (i *Identity) CanAccessProject(project string) bool {
+	return models.RoleAtLeast(i.RoleFor(project), models.RoleViewer)
+}
+
+// CanDeploy 是否可以对指定项目执行检出、回退等变更操作
+func (i *Identity) CanDeploy(project string) bool {
+	return models.RoleAtLeast(i.RoleFor(project), models.RoleDeployer)
+}
+
+// visibleProjects 返回身份可以查看的已启用项目
+func visibleProjects(identity *Identity) []models.Project {
+	var projects []models.Project
+	for _, project := range models.GetConfig().GetEnabledProjects() {
+		if identity.CanAccessProject(project.Name) {
+			projects = append(projects, project)
+		}
+	}
+	return projects
+}
+
+// bearerToken 从 Authorization 头中提取 Bearer 令牌
+func bearerToken(c *web.Controller) (string, bool) {
+	return parseBearerToken(c.Ctx.Input.Header("Authorization"))
+}
+
+// parseBearerToken 解析 Authorization 头中的 Bearer 令牌
+func parseBearerToken(header string) (string, bool) {
+	if header == "" {
+		return "", false
+	}
+	const prefix = "bearer "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+// authenticateRequest 认证请求：优先使用 Bearer 令牌，其次使用登录会话
+// 携带了令牌但令牌无效时直接认证失败，不回退到会话认证
+func authenticateRequest(c *web.Controller) *Identity {
+	if token, ok := bearerToken(c); ok {
+		apiToken := models.GetConfig().FindAPIToken(token)
+		if apiToken == nil {
+			return nil
+		}
+		return &Identity{Name: apiToken.Name, Token: apiToken}
+	}
+
+	return sessionIdentity(c)
+}
+
+// sessionIdentity 根据登录会话获取身份，未登录或用户已被删除时返回 nil
+func sessionIdentity(c *web.Controller) *Identity {
+	if !isAuthenticated(c) {
+		return nil
+	}
+	user := models.GetConfig().FindUser(currentUsername(c))
+	if user == nil {
+		return nil
+	}
+	return &Identity{Name: user.Username, User: user}
+}
+
+// requireJSONAuth 要求 JSON 接口已认证（会话或令牌），失败时返回 401 JSON
+func requireJSONAuth(c *web.Controller) *Identity {
+	identity := authenticateRequest(c)
+	if identity == nil {
+		c.Ctx.Output.SetStatus(http.StatusUnauthorized)
+		c.Data["json"] = map[string]interface{}{
+			"success": false,
+			"message": "未登录或令牌无效",
+		}
+		c.ServeJSON()
+	}
+	return identity
+}
+
+// filterHistoryForIdentity 过滤掉身份无权查看的项目历史
+func filterHistoryForIdentity(identity *Identity, entries []models.HistoryEntry) []models.HistoryEntry {
+	filtered := []models.HistoryEntry{}
+	for _, entry := range entries {
+		if identity.CanAccessProject(entry.Project) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"fmt"
+	"gover/models"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// 登录防暴力破解的默认值
+const (
+	defaultLoginMaxAttempts      = 5
+	defaultLoginMaxAttemptsPerIP = 20
+	defaultLoginBackoff          = time.Second
+	defaultLoginLockout          = 15 * time.Minute
+)
+
+// 登录失败记录的类型
+const (
+	lockoutKindUser = "user"
+	lockoutKindIP   = "ip"
+)
+
+// LoginLockout 按用户名或 IP 统计的登录失败记录
+type LoginLockout struct {
+	Key          string    `json:"key"`           // 记录键，如 user:admin、ip:10.0.0.1
+	Kind         string    `json:"kind"`          // user 或 ip
+	Value        string    `json:"value"`         // 用户名或 IP
+	Failures     int       `json:"failures"`      // 连续失败次数
+	LastFailure  time.Time `json:"last_failure"`  // 最近一次失败时间
+	BlockedUntil time.Time `json:"blocked_until"` // 在此之前拒绝登录尝试
+	Locked       bool      `json:"locked"`        // 是否因达到最大次数被锁定（而不只是退避等待）
+	Pending      int       `json:"-"`             // 正在校验密码的尝试次数
+}
+
+// loginGuard 登录失败跟踪器（内存中，重启后清空）
+type loginGuard struct {
+	mu      sync.Mutex
+	entries map[string]*LoginLockout
+}
+
+// loginAttempts 全局登录失败跟踪器
+var loginAttempts = &loginGuard{entries: map[string]*LoginLockout{}}
+
+// loginLimits 读取防暴力破解配置，未配置时使用默认值
+func loginLimits() (userMax, ipMax int, backoff, lockout time.Duration) {
+	security := models.GetConfig().Security
+	userMax, ipMax = defaultLoginMaxAttempts, defaultLoginMaxAttemptsPerIP
+	backoff, lockout = defaultLoginBackoff, defaultLoginLockout
+	if security.LoginMaxAttempts > 0 {
+		userMax = security.LoginMaxAttempts
+	}
+	if security.LoginMaxAttemptsPerIP > 0 {
+		ipMax = security.LoginMaxAttemptsPerIP
+	}
+	if security.LoginBackoff > 0 {
+		backoff = time.Duration(security.LoginBackoff) * time.Second
+	}
+	if security.LoginLockout > 0 {
+		lockout = time.Duration(security.LoginLockout) * time.Second
+	}
+	return userMax, ipMax, backoff, lockout
+}
+
+// lockoutKey 生成记录键
+func lockoutKey(kind, value string) string {
+	return kind + ":" + value
+}
+
+// reserve 在校验密码前占用一次尝试机会，返回还需等待多久才能再次尝试，0 表示已占用，校验完成后必须调用 release
+// 检查和占用在同一把锁内完成：同一用户名同时只允许一个尝试，IP 的失败次数加上正在校验的次数不能超过上限，
+// 并发请求无法在失败被记录之前绕过退避和锁定
+func (g *loginGuard) reserve(ip, username string) time.Duration {
+	_, ipMax, backoff, _ := loginLimits()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	keys := []string{lockoutKey(lockoutKindIP, ip)}
+	if username != "" {
+		keys = append(keys, lockoutKey(lockoutKindUser, username))
+	}
+
+	var wait time.Duration
+	for _, key := range keys {
+		entry := g.entries[key]
+		if entry == nil {
+			continue
+		}
+		if now.Before(entry.BlockedUntil) {
+			if remaining := entry.BlockedUntil.Sub(now); remaining > wait {
+				wait = remaining
+			}
+		}
+		busy := entry.Pending > 0
+		if entry.Kind == lockoutKindIP {
+			busy = entry.Failures+entry.Pending >= ipMax
+		}
+		if busy && wait < backoff {
+			wait = backoff
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	g.entry(lockoutKindIP, ip).Pending++
+	if username != "" {
+		g.entry(lockoutKindUser, username).Pending++
+	}
+	return 0
+}
+
+// release 释放 reserve 占用的尝试机会并记录结果：失败时累计失败次数，成功时清除该用户名的失败记录（IP 记录按时间自然过期）
+func (g *loginGuard) release(ip, username string, success bool) {
+	userMax, ipMax, backoff, lockout := loginLimits()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	keys := []string{lockoutKey(lockoutKindIP, ip)}
+	if username != "" {
+		keys = append(keys, lockoutKey(lockoutKindUser, username))
+	}
+	for _, key := range keys {
+		// 管理员可能在校验期间清除了记录
+		if entry := g.entries[key]; entry != nil && entry.Pending > 0 {
+			entry.Pending--
+		}
+	}
+
+	if success {
+		if entry := g.entries[lockoutKey(lockoutKindUser, username)]; entry != nil && entry.Pending == 0 {
+			delete(g.entries, entry.Key)
+		}
+	} else {
+		g.fail(now, lockoutKindIP, ip, ipMax, backoff, lockout)
+		if username != "" {
+			g.fail(now, lockoutKindUser, username, userMax, backoff, lockout)
+		}
+	}
+	g.prune(now, lockout)
+}
+
+// entry 返回记录，不存在时创建
+func (g *loginGuard) entry(kind, value string) *LoginLockout {
+	key := lockoutKey(kind, value)
+	entry := g.entries[key]
+	if entry == nil {
+		entry = &LoginLockout{Key: key, Kind: kind, Value: value}
+		g.entries[key] = entry
+	}
+	return entry
+}
+
+// fail 增加失败次数并计算下一次允许尝试的时间：未达上限时按 backoff*2^(n-1) 退避，达到上限时锁定
+func (g *loginGuard) fail(now time.Time, kind, value string, maxAttempts int, backoff, lockout time.Duration) {
+	entry := g.entry(kind, value)
+	entry.Failures++
+	entry.LastFailure = now
+
+	if entry.Failures >= maxAttempts {
+		entry.BlockedUntil = now.Add(lockout)
+		if !entry.Locked {
+			entry.Locked = true
+			fmt.Printf("🔒 登录已锁定: %s %s 连续失败 %d 次，锁定至 %s\n",
+				lockoutKindLabel(kind), value, entry.Failures, entry.BlockedUntil.Format("2006-01-02 15:04:05"))
+		}
+		return
+	}
+
+	delay := backoff
+	for i := 1; i < entry.Failures && delay < lockout; i++ {
+		delay *= 2
+	}
+	if delay > lockout {
+		delay = lockout
+	}
+	entry.BlockedUntil = now.Add(delay)
+}
+
+// prune 清理已过期的记录：没有正在校验的尝试、不在等待期内且最近一次失败已超过锁定时长
+func (g *loginGuard) prune(now time.Time, lockout time.Duration) {
+	for key, entry := range g.entries {
+		if entry.Pending == 0 && !now.Before(entry.BlockedUntil) && now.Sub(entry.LastFailure) > lockout {
+			delete(g.entries, key)
+		}
+	}
+}
+
+// list 返回当前仍然有效的失败记录，被锁定的排在前面
+func (g *loginGuard) list() []LoginLockout {
+	_, _, _, lockout := loginLimits()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.prune(time.Now(), lockout)
+	entries := make([]LoginLockout, 0, len(g.entries))
+	for _, entry := range g.entries {
+		// 只有正在校验、还没有失败过的记录不显示
+		if entry.Failures > 0 {
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Locked != entries[j].Locked {
+			return entries[i].Locked
+		}
+		return entries[i].LastFailure.After(entries[j].LastFailure)
+	})
+	return entries
+}
+
+// clear 清除指定记录，key 为空时清除全部，返回清除的数量
+func (g *loginGuard) clear(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if key == "" {
+		count := len(g.entries)
+		g.entries = map[string]*LoginLockout{}
+		return count
+	}
+	if _, ok := g.entries[key]; !ok {
+		return 0
+	}
+	delete(g.entries, key)
+	return 1
+}
+
+// lockoutKindLabel 记录类型的中文名称
+func lockoutKindLabel(kind string) string {
+	if kind == lockoutKindIP {
+		return "IP"
+	}
+	return "用户"
+}
+
+// formatWait 将等待时间格式化为便于阅读的文本
+func formatWait(wait time.Duration) string {
+	if wait < time.Minute {
+		return fmt.Sprintf("%d 秒", int((wait+time.Second-1)/time.Second))
+	}
+	return fmt.Sprintf("%d 分钟", int((wait+time.Minute-1)/time.Minute))
+}
+
+// clientIP 获取客户端 IP
+func clientIP(c *web.Controller) string {
+	return requestIP(c.Ctx.Request)
+}
+
+// requestIP 获取请求的客户端 IP；开启 trust_proxy 时优先使用反向代理设置的 X-Real-IP / X-Forwarded-For
+func requestIP(r *http.Request) string {
+	if models.GetConfig().Security.TrustProxy {
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			// 最右侧的地址由最近一层代理添加，不能被客户端伪造
+			parts := strings.Split(forwarded, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 用户 3 次、IP 5 次失败后锁定，退避 1 秒起，锁定 60 秒
+const testLoginConfig = `server:
+  port: 8080
+users:
+  - username: "admin"
+    password: "$2a$10$uYhnbrCycfiMwwu4R9vuWOiM2CdBw7jondfbL4e3cDlbnblP6SaNK"
+    role: "admin"
+security:
+  session_timeout: 3600
+  login_max_attempts: 3
+  login_max_attempts_per_ip: 5
+  login_backoff: 1
+  login_lockout: 60
+`
+
+func newTestLoginGuard() *loginGuard {
+	return &loginGuard{entries: map[string]*LoginLockout{}}
+}
+
+// elapse 模拟时间流逝：将所有记录的等待截止时间和最近失败时间提前 d
+func (g *loginGuard) elapse(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, entry := range g.entries {
+		entry.BlockedUntil = entry.BlockedUntil.Add(-d)
+		entry.LastFailure = entry.LastFailure.Add(-d)
+	}
+}
+
+// loginStep 一次登录尝试或一段时间流逝
+type loginStep struct {
+	ip, user    string
+	op          string        // fail、succeed 或 elapse
+	elapse      time.Duration // op 为 elapse 时流逝的时间
+	wantAllowed bool          // 尝试是否被允许（不处于退避或锁定期间）
+}
+
+// loginFail 一次密码错误的登录尝试，allowed 为期望是否允许尝试
+func loginFail(ip, user string, allowed bool) loginStep {
+	return loginStep{ip: ip, user: user, op: "fail", wantAllowed: allowed}
+}
+
+// loginSucceed 一次密码正确的登录尝试
+func loginSucceed(ip, user string, allowed bool) loginStep {
+	return loginStep{ip: ip, user: user, op: "succeed", wantAllowed: allowed}
+}
+
+// loginElapse 流逝一段时间
+func loginElapse(d time.Duration) loginStep {
+	return loginStep{op: "elapse", elapse: d}
+}
+
+func TestLoginGuardSequences(t *testing.T) {
+	useTestConfigData(t, testLoginConfig)
+
+	tests := []struct {
+		name         string
+		steps        []loginStep
+		wantFailures map[string]int  // 结束时各记录的失败次数，0 表示记录已清除
+		wantLocked   map[string]bool // 结束时各记录是否被锁定
+	}{
+		{
+			name: "退避期间拒绝尝试，退避时间按次数翻倍",
+			steps: []loginStep{
+				loginFail("1.1.1.1", "admin", true),
+				loginFail("1.1.1.1", "admin", false),
+				loginElapse(time.Second),
+				loginFail("1.1.1.1", "admin", true),
+				loginElapse(time.Second),
+				loginFail("1.1.1.1", "admin", false),
+				loginElapse(time.Second),
+				loginSucceed("1.1.1.1", "admin", true),
+			},
+			wantFailures: map[string]int{"user:admin": 0, "ip:1.1.1.1": 2},
+		},
+		{
+			name: "达到上限后锁定，锁定期满后可以重试",
+			steps: []loginStep{
+				loginFail("1.1.1.1", "admin", true),
+				loginElapse(time.Second),
+				loginFail("1.1.1.1", "admin", true),
+				loginElapse(2 * time.Second),
+				loginFail("1.1.1.1", "admin", true),
+				loginElapse(30 * time.Second),
+				loginFail("1.1.1.1", "admin", false),
+				loginElapse(31 * time.Second),
+				loginFail("1.1.1.1", "admin", true),
+			},
+			wantFailures: map[string]int{"user:admin": 4, "ip:1.1.1.1": 4},
+			wantLocked:   map[string]bool{"user:admin": true},
+		},
+		{
+			name: "成功登录清除用户记录，IP 记录保留",
+			steps: []loginStep{
+				loginFail("1.1.1.1", "admin", true),
+				loginElapse(time.Second),
+				loginFail("1.1.1.1", "admin", true),
+				loginElapse(2 * time.Second),
+				loginSucceed("1.1.1.1", "admin", true),
+				loginElapse(time.Second),
+				loginFail("1.1.1.1", "admin", true),
+			},
+			wantFailures: map[string]int{"user:admin": 1, "ip:1.1.1.1": 3},
+			wantLocked:   map[string]bool{"user:admin": false},
+		},
+		{
+			name: "用户的退避对所有 IP 生效",
+			steps: []loginStep{
+				loginFail("1.1.1.1", "admin", true),
+				loginFail("2.2.2.2", "admin", false),
+				loginSucceed("2.2.2.2", "other", true),
+			},
+			wantFailures: map[string]int{"user:admin": 1, "ip:1.1.1.1": 1, "ip:2.2.2.2": 0},
+		},
+		{
+			name: "同一 IP 尝试不同用户名达到 IP 上限后锁定",
+			steps: []loginStep{
+				loginFail("1.1.1.1", "u1", true),
+				loginElapse(time.Second),
+				loginFail("1.1.1.1", "u2", true),
+				loginElapse(2 * time.Second),
+				loginFail("1.1.1.1", "u3", true),
+				loginElapse(4 * time.Second),
+				loginFail("1.1.1.1", "u4", true),
+				loginElapse(8 * time.Second),
+				loginFail("1.1.1.1", "u5", true),
+				loginElapse(30 * time.Second),
+				loginSucceed("1.1.1.1", "admin", false),
+				loginSucceed("2.2.2.2", "admin", true),
+			},
+			wantFailures: map[string]int{"ip:1.1.1.1": 5, "user:u5": 1, "user:admin": 0},
+			wantLocked:   map[string]bool{"ip:1.1.1.1": true, "user:u5": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestLoginGuard()
+			for i, step := range tt.steps {
+				if step.op == "elapse" {
+					g.elapse(step.elapse)
+					continue
+				}
+				wait := g.reserve(step.ip, step.user)
+				if allowed := wait == 0; allowed != step.wantAllowed {
+					t.Fatalf("第 %d 步 %s %s@%s: 允许=%v（等待 %s），期望 %v", i+1, step.op, step.user, step.ip, allowed, wait, step.wantAllowed)
+				}
+				if wait == 0 {
+					g.release(step.ip, step.user, step.op == "succeed")
+				}
+			}
+
+			for key, want := range tt.wantFailures {
+				got := 0
+				if entry := g.entries[key]; entry != nil {
+					got = entry.Failures
+				}
+				if got != want {
+					t.Errorf("%s 失败次数为 %d，期望 %d", key, got, want)
+				}
+			}
+			for key, want := range tt.wantLocked {
+				entry := g.entries[key]
+				if got := entry != nil && entry.Locked; got != want {
+					t.Errorf("%s 锁定状态为 %v，期望 %v", key, got, want)
+				}
+			}
+			for key, entry := range g.entries {
+				if entry.Pending != 0 {
+					t.Errorf("%s 仍有 %d 个未释放的尝试", key, entry.Pending)
+				}
+			}
+		})
+	}
+}
+
+// reserveConcurrently 让 n 个请求同时占用尝试机会，返回成功占用的用户名
+func reserveConcurrently(g *loginGuard, ip string, username func(i int) string, n int) []string {
+	var (
+		start   = make(chan struct{})
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		granted []string
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			<-start
+			if g.reserve(ip, user) == 0 {
+				mu.Lock()
+				granted = append(granted, user)
+				mu.Unlock()
+			}
+		}(username(i))
+	}
+	close(start)
+	wg.Wait()
+	return granted
+}
+
+func TestLoginGuardConcurrentReserve(t *testing.T) {
+	useTestConfigData(t, testLoginConfig)
+
+	t.Run("同一用户名同时只允许一个尝试", func(t *testing.T) {
+		g := newTestLoginGuard()
+		granted := reserveConcurrently(g, "1.1.1.1", func(int) string { return "admin" }, 20)
+		if len(granted) != 1 {
+			t.Fatalf("%d 个并发尝试被允许，期望 1 个", len(granted))
+		}
+		g.release("1.1.1.1", "admin", false)
+		if wait := g.reserve("1.1.1.1", "admin"); wait == 0 {
+			t.Error("失败后应进入退避")
+		}
+	})
+
+	t.Run("同一 IP 的并发尝试不超过 IP 上限", func(t *testing.T) {
+		g := newTestLoginGuard()
+		granted := reserveConcurrently(g, "1.1.1.1", func(i int) string { return fmt.Sprintf("u%d", i) }, 20)
+		if len(granted) != 5 {
+			t.Fatalf("%d 个并发尝试被允许，期望 IP 上限 5 个", len(granted))
+		}
+
+		var wg sync.WaitGroup
+		for _, user := range granted {
+			wg.Add(1)
+			go func(user string) {
+				defer wg.Done()
+				g.release("1.1.1.1", user, false)
+			}(user)
+		}
+		wg.Wait()
+
+		entry := g.entries["ip:1.1.1.1"]
+		if entry == nil || entry.Failures != 5 || !entry.Locked || entry.Pending != 0 {
+			t.Fatalf("全部失败后 IP 应被锁定，实际记录: %+v", entry)
+		}
+		if wait := g.reserve("1.1.1.1", "admin"); wait <= 30*time.Second {
+			t.Errorf("IP 锁定后等待时间为 %s，期望接近锁定时长 60 秒", wait)
+		}
+	})
+
+	t.Run("管理员在校验期间清除记录", func(t *testing.T) {
+		g := newTestLoginGuard()
+		if wait := g.reserve("1.1.1.1", "admin"); wait != 0 {
+			t.Fatalf("首次尝试被拒绝，需等待 %s", wait)
+		}
+		g.clear("")
+		g.release("1.1.1.1", "admin", true)
+		for key, entry := range g.entries {
+			if entry.Pending < 0 {
+				t.Errorf("%s 的 Pending 为负数", key)
+			}
+		}
+		if wait := g.reserve("1.1.1.1", "admin"); wait != 0 {
+			t.Errorf("清除记录并成功登录后应允许尝试，需等待 %s", wait)
+		}
+	})
+}
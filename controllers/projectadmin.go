@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"gover/models"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// Projects 显示所有项目（包括已禁用的项目）
+func (c *AdminController) Projects() {
+	if c.requireAdmin() == nil {
+		return
+	}
+
+	c.readFlash()
+	c.Data["Projects"] = models.GetConfig().Projects
+	c.Data["ConfigPath"] = models.ConfigPath()
+	c.Data["Title"] = "项目管理 - " + models.GetConfig().UI.Title
+	c.TplName = "admin/projects.html"
+}
+
+// EditProject 显示新增或编辑项目的表单，name 为空时新增
+func (c *AdminController) EditProject() {
+	if c.requireAdmin() == nil {
+		return
+	}
+
+	name := c.GetString("name")
+	project := models.Project{Enabled: true}
+	if name != "" {
+		found := false
+		for _, p := range models.GetConfig().Projects {
+			if p.Name == name {
+				project, found = p, true
+				break
+			}
+		}
+		if !found {
+			c.projectFlash("", fmt.Sprintf("项目 %s 不存在", name))
+			return
+		}
+	}
+	c.renderProjectForm(name, project, "")
+}
+
+// SaveProject 保存新增或编辑的项目，写回配置文件后立即生效
+func (c *AdminController) SaveProject() {
+	identity := c.requireAdmin()
+	if identity == nil {
+		return
+	}
+
+	originalName := c.GetString("original_name")
+	project := models.Project{
+		Name:        strings.TrimSpace(c.GetString("name")),
+		Path:        strings.TrimSpace(c.GetString("path")),
+		Description: strings.TrimSpace(c.GetString("description")),
+		Enabled:     c.GetString("enabled") == "on",
+		Group:       strings.TrimSpace(c.GetString("group")),
+		Environment: c.GetString("environment"),
+		GitBackend:  c.GetString("git_backend"),
+		DirtyPolicy: c.GetString("dirty_policy"),
+	}
+
+	if project.Name == "" {
+		c.renderProjectForm(originalName, project, "项目名称不能为空")
+		return
+	}
+	if err := checkProjectPath(c.Ctx.Request.Context(), project); err != nil {
+		c.renderProjectForm(originalName, project, err.Error())
+		return
+	}
+	if err := models.SaveProject(originalName, project); err != nil {
+		c.renderProjectForm(originalName, project, err.Error())
+		return
+	}
+
+	if originalName == "" {
+		fmt.Printf("📁 管理员 %s 新增了项目 %s (%s)\n", identity.Name, project.Name, project.Path)
+		c.projectFlash(fmt.Sprintf("已新增项目 %s", project.Name), "")
+	} else {
+		fmt.Printf("📁 管理员 %s 修改了项目 %s (%s)\n", identity.Name, project.Name, project.Path)
+		c.projectFlash(fmt.Sprintf("已保存项目 %s", project.Name), "")
+	}
+}
+
+// ToggleProject 启用或禁用项目
+func (c *AdminController) ToggleProject() {
+	identity := c.requireAdmin()
+	if identity == nil {
+		return
+	}
+
+	name := c.GetString("name")
+	enabled := c.GetString("enabled") == "true"
+	if enabled {
+		for _, project := range models.GetConfig().Projects {
+			if project.Name == name {
+				if err := checkProjectPath(c.Ctx.Request.Context(), project); err != nil {
+					c.projectFlash("", fmt.Sprintf("无法启用项目 %s: %v", name, err))
+					return
+				}
+			}
+		}
+	}
+
+	if err := models.SetProjectEnabled(name, enabled); err != nil {
+		c.projectFlash("", err.Error())
+		return
+	}
+	action := "禁用"
+	if enabled {
+		action = "启用"
+	}
+	fmt.Printf("📁 管理员 %s %s了项目 %s\n", identity.Name, action, name)
+	c.projectFlash(fmt.Sprintf("已%s项目 %s", action, name), "")
+}
+
+// DeleteProject 从配置文件中删除项目（不会删除项目目录）
+func (c *AdminController) DeleteProject() {
+	identity := c.requireAdmin()
+	if identity == nil {
+		return
+	}
+
+	name := c.GetString("name")
+	if err := models.DeleteProject(name); err != nil {
+		c.projectFlash("", err.Error())
+		return
+	}
+	fmt.Printf("🗑️ 管理员 %s 删除了项目 %s\n", identity.Name, name)
+	c.projectFlash(fmt.Sprintf("已删除项目 %s（项目目录未删除）", name), "")
+}
+
+// renderProjectForm 显示项目表单，保存失败时保留用户输入
+func (c *AdminController) renderProjectForm(originalName string, project models.Project, message string) {
+	c.Data["OriginalName"] = originalName
+	c.Data["Project"] = project
+	c.Data["Environments"] = models.Environments
+	c.Data["Error"] = message
+	if originalName == "" {
+		c.Data["Title"] = "新增项目 - " + models.GetConfig().UI.Title
+	} else {
+		c.Data["Title"] = "编辑项目 - " + models.GetConfig().UI.Title
+	}
+	c.TplName = "admin/project_form.html"
+}
+
+// projectFlash 保存 flash 消息并返回项目列表
+func (c *AdminController) projectFlash(success, failure string) {
+	flash := web.NewFlash()
+	if failure != "" {
+		flash.Error("%s", failure)
+	} else {
+		flash.Success("%s", success)
+	}
+	flash.Store(&c.Controller)
+	c.Redirect("/admin/projects", 302)
+}
+
+// checkProjectPath 检查项目路径是已存在的 Git 仓库目录，并且项目使用的 Git 后端可以读取
+func checkProjectPath(ctx context.Context, project models.Project) error {
+	path := project.Path
+	if path == "" {
+		return fmt.Errorf("项目路径不能为空")
+	}
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("项目路径必须是绝对路径: %s", path)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("项目路径不存在: %s", path)
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("项目路径不是目录: %s", path)
+	}
+	if _, err := gitBackendFor(project).Head(ctx, path); err != nil {
+		return fmt.Errorf("项目路径不是 Git 仓库: %s", path)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gover/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 项目锁相关配置
+var (
+	ProjectLockWait = 5 * time.Second // 项目正忙时等待锁释放的最长时间，超时后拒绝操作
+
+	projectLockPoll = 200 * time.Millisecond // 等待期间检查锁的间隔
+
+	projectLockWriteGrace = 10 * time.Second // 无法解析的锁文件超过该时间视为过期（进程在创建锁文件和写入内容之间崩溃）
+)
+
+// 项目锁的文件名，位于仓库的 .git 目录中，不会出现在工作区的修改列表里
+const projectLockFile = "gover.lock"
+
+// ProjectLock 项目锁的持有者信息
+type ProjectLock struct {
+	Project   string    `json:"project"`
+	Operation string    `json:"operation"` // 正在执行的操作，如“检出标签 v1.2.0”
+	User      string    `json:"user"`      // 发起操作的用户，后台任务为“系统”
+	Since     time.Time `json:"since"`
+	Host      string    `json:"host"` // 持有锁的 gover 实例所在主机
+	PID       int       `json:"pid"`  // 持有锁的 gover 进程
+}
+
+// ProjectBusyError 项目正在执行其他操作
+type ProjectBusyError struct {
+	Holder ProjectLock
+}
+
+// Error 返回包含操作和用户的提示
+func (e *ProjectBusyError) Error() string {
+	return fmt.Sprintf("项目 %s 正忙：%s 正在执行%s（开始于 %s），请稍后重试",
+		e.Holder.Project, e.Holder.User, e.Holder.Operation, e.Holder.Since.Format("2006-01-02 15:04:05"))
+}
+
+// 进程内的项目锁，按项目路径区分，同一工作区只允许一个变更操作
+var (
+	projectLocks      = make(map[string]*ProjectLock)
+	projectLocksMutex sync.Mutex
+)
+
+// lockProject 获取项目锁，项目正忙时最多等待 ProjectLockWait，返回释放锁的函数
+// 锁同时写入仓库中的锁文件，多个 gover 实例管理同一仓库时同样互斥
+func lockProject(ctx context.Context, project models.Project, operation, user string) (func(), error) {
+	deadline := time.Now().Add(ProjectLockWait)
+	for {
+		unlock, err := tryLockProject(project, operation, user)
+		var busy *ProjectBusyError
+		if err == nil || !errors.As(err, &busy) || time.Now().After(deadline) {
+			return unlock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(projectLockPoll):
+		}
+	}
+}
+
+// tryLockProject 获取项目锁，项目正忙时立即返回 ProjectBusyError
+func tryLockProject(project models.Project, operation, user string) (func(), error) {
+	host, _ := os.Hostname()
+	lock := &ProjectLock{
+		Project:   project.Name,
+		Operation: operation,
+		User:      user,
+		Since:     time.Now(),
+		Host:      host,
+		PID:       os.Getpid(),
+	}
+
+	projectLocksMutex.Lock()
+	defer projectLocksMutex.Unlock()
+
+	if holder, ok := projectLocks[project.Path]; ok {
+		return nil, &ProjectBusyError{Holder: *holder}
+	}
+
+	lockPath := projectLockPath(project.Path)
+	if err := createLockFile(lockPath, lock); err != nil {
+		return nil, err
+	}
+	projectLocks[project.Path] = lock
+
+	return func() {
+		projectLocksMutex.Lock()
+		defer projectLocksMutex.Unlock()
+
+		delete(projectLocks, project.Path)
+		if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("⚠️ 删除项目 %s 的锁文件失败: %v\n", project.Name, err)
+		}
+	}, nil
+}
+
+// createLockFile 创建锁文件，文件已存在且持有者仍在运行时返回 ProjectBusyError
+func createLockFile(lockPath string, lock *ProjectLock) error {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = file.Write(data)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(lockPath)
+				return fmt.Errorf("写入锁文件 %s 失败: %v", lockPath, err)
+			}
+			return nil
+		}
+		if !os.IsExist(err) {
+			return fmt.Errorf("创建锁文件 %s 失败: %v", lockPath, err)
+		}
+
+		holder, stale := readLockFile(lockPath)
+		if !stale {
+			if holder.Project == "" {
+				holder.Project = lock.Project
+			}
+			return &ProjectBusyError{Holder: holder}
+		}
+
+		// 持有者已经退出（例如进程崩溃），清理后重试
+		fmt.Printf("🔓 清理项目 %s 的过期锁文件（%s 的 %s，进程 %d）\n", lock.Project, holder.User, holder.Operation, holder.PID)
+		if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("清理过期锁文件 %s 失败: %v", lockPath, err)
+		}
+	}
+	return fmt.Errorf("创建锁文件 %s 失败: 文件被反复占用", lockPath)
+}
+
+// readLockFile 读取锁文件，返回持有者信息以及锁是否已过期
+// 同一主机上持有锁的进程已经退出，或锁文件为空、无法解析且超过 projectLockWriteGrace 时视为过期，
+// 其他主机的锁需要手动删除
+func readLockFile(lockPath string) (ProjectLock, bool) {
+	var holder ProjectLock
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		// 锁文件刚被删除，重试即可
+		return holder, os.IsNotExist(err)
+	}
+	if err := json.Unmarshal(data, &holder); err != nil {
+		// 持有者可能刚创建锁文件还没写入内容，等待一段时间后再视为过期
+		holder = ProjectLock{User: "未知用户", Operation: "未知操作"}
+		stat, err := os.Stat(lockPath)
+		if err != nil {
+			return holder, os.IsNotExist(err)
+		}
+		holder.Since = stat.ModTime()
+		return holder, time.Since(holder.Since) > projectLockWriteGrace
+	}
+
+	host, _ := os.Hostname()
+	if holder.Host != host {
+		return holder, false
+	}
+	// 当前进程持有的锁都记录在 projectLocks 中，锁文件属于当前进程时说明是上次运行残留的
+	if holder.PID == os.Getpid() {
+		return holder, true
+	}
+	return holder, !processAlive(holder.PID)
+}
+
+// projectLockPath 返回项目锁文件的路径
+func projectLockPath(projectPath string) string {
+	return filepath.Join(projectGitDir(projectPath), projectLockFile)
+}
+
+// projectGitDir 返回项目的 Git 目录，支持 .git 为文件的工作树和子模块
+func projectGitDir(projectPath string) string {
+	gitPath := filepath.Join(projectPath, ".git")
+	if data, err := os.ReadFile(gitPath); err == nil {
+		if gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:"); ok {
+			gitDir = strings.TrimSpace(gitDir)
+			if !filepath.IsAbs(gitDir) {
+				gitDir = filepath.Join(projectPath, gitDir)
+			}
+			return gitDir
+		}
+	}
+	return gitPath
+}
+
+// projectBusy 返回项目当前的锁持有者，没有被锁定时返回 nil
+func projectBusy(project models.Project) *ProjectLock {
+	projectLocksMutex.Lock()
+	defer projectLocksMutex.Unlock()
+
+	if holder, ok := projectLocks[project.Path]; ok {
+		lock := *holder
+		return &lock
+	}
+	if holder, stale := readLockFile(projectLockPath(project.Path)); !stale {
+		if holder.Project == "" {
+			holder.Project = project.Name
+		}
+		return &holder
+	}
+	return nil
+}
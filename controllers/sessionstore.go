@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"gover/models"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// anonymousSessionMaxAge 会话 Cookie 的默认有效期(秒)，也是未登录时 CSRF 令牌 Cookie 的有效期
+const anonymousSessionMaxAge = 24 * 3600
+
+// serverSessionStore 服务端会话存储：Cookie 中只保存签名后的会话 ID，会话数据及元信息保存在数据目录中
+type serverSessionStore struct {
+	Codecs      []securecookie.Codec
+	Options     *sessions.Options
+	records     *models.SessionStore
+	keysModTime time.Time // 加载签名密钥时密钥文件的修改时间
+}
+
+// newServerSessionStore 创建服务端会话存储，签名密钥通过 setKeys 设置
+func newServerSessionStore(records *models.SessionStore) *serverSessionStore {
+	return &serverSessionStore{
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   anonymousSessionMaxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		},
+		records: records,
+	}
+}
+
+// setKeys 设置签名密钥：第一个密钥用于签名，所有密钥都可用于验证，轮换密钥时已登录的会话不受影响
+func (s *serverSessionStore) setKeys(keys [][]byte) {
+	pairs := make([][]byte, 0, len(keys)*2)
+	for _, key := range keys {
+		// 会话数据保存在服务端，只需要签名，不需要加密
+		pairs = append(pairs, key, nil)
+	}
+	s.Codecs = securecookie.CodecsFromPairs(pairs...)
+}
+
+// Get 获取会话，同一请求内多次获取返回同一个对象
+func (s *serverSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New 根据 Cookie 中的会话 ID 加载会话，ID 无效、会话已过期或已被吊销时返回新会话
+func (s *serverSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := *s.Options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var id string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &id, s.Codecs...); err != nil {
+		return session, err
+	}
+	record, err := s.records.Get(id)
+	if err != nil || record == nil {
+		return session, err
+	}
+	if err := securecookie.DecodeMulti(name, record.Data, &session.Values, s.Codecs...); err != nil {
+		return session, err
+	}
+
+	session.ID = id
+	session.IsNew = false
+	if err := s.records.Touch(id, requestIP(r), r.UserAgent()); err != nil {
+		fmt.Printf("⚠️ 更新会话活动时间失败: %v\n", err)
+	}
+	return session, nil
+}
+
+// Save 保存会话数据到服务端并写入会话 ID Cookie；MaxAge < 0 或未登录时删除会话
+// 服务端只保存已登录的会话，匿名请求不会写入会话文件
+func (s *serverSessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	authenticated, _ := session.Values["authenticated"].(bool)
+	if session.Options.MaxAge < 0 || !authenticated {
+		if session.ID != "" {
+			id := session.ID
+			if _, err := s.records.Delete(func(record *models.SessionRecord) bool { return record.ID == id }); err != nil {
+				return err
+			}
+		}
+		options := *session.Options
+		options.MaxAge = -1
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", &options))
+		return nil
+	}
+
+	now := time.Now()
+	record := models.SessionRecord{
+		ID:           session.ID,
+		IP:           requestIP(r),
+		UserAgent:    r.UserAgent(),
+		CreatedAt:    now,
+		LastActivity: now,
+	}
+	if record.ID == "" {
+		record.ID = newSessionID()
+	} else if existing, err := s.records.Get(record.ID); err == nil && existing != nil {
+		record.CreatedAt = existing.CreatedAt
+	}
+	record.Username, _ = session.Values["username"].(string)
+
+	// MaxAge 为 0 时 Cookie 在浏览器关闭时失效，服务端按会话超时时间清理
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		maxAge = models.GetConfig().Security.SessionTimeout
+	}
+	if maxAge > 0 {
+		record.ExpiresAt = now.Add(time.Duration(maxAge) * time.Second)
+	}
+
+	data, err := securecookie.EncodeMulti(session.Name(), session.Values, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	record.Data = data
+	if err := s.records.Put(record); err != nil {
+		return err
+	}
+
+	encodedID, err := securecookie.EncodeMulti(session.Name(), record.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	session.ID = record.ID
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encodedID, session.Options))
+	return nil
+}
+
+// renewSessionID 登录时更换会话 ID 并删除旧会话，防止会话固定攻击
+func (s *serverSessionStore) renewSessionID(session *sessions.Session) {
+	if session.ID == "" {
+		return
+	}
+	id := session.ID
+	if _, err := s.records.Delete(func(record *models.SessionRecord) bool { return record.ID == id }); err != nil {
+		fmt.Printf("⚠️ 删除旧会话失败: %v\n", err)
+	}
+	session.ID = ""
+}
+
+// newSessionID 生成随机会话 ID
+func newSessionID() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("生成会话 ID 失败: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// sessionRecords 返回服务端会话记录存储
+func sessionRecords() *models.SessionStore {
+	return sessionStore().records
+}
+
+// currentSessionID 返回当前请求的会话 ID
+func currentSessionID(r *http.Request) string {
+	session, err := sessionStore().Get(r, "gogo-session")
+	if err != nil {
+		return ""
+	}
+	return session.ID
+}
@@ -1,14 +1,14 @@
 package controllers
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"gover/models"
+	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -37,41 +37,87 @@ type ProjectCacheItem struct {
 // 性能配置
 var (
 	SkipFetch     = false // 是否跳过 fetch 操作
-	FastMode      = false // 快速模式：只获取基本信息
 	MaxConcurrent = 3     // 最大并发数
 )
 
 // TagInfo 存储标签信息
 type TagInfo struct {
-	Name        string
-	Checked     bool
-	CreatedTime string
-	Message     string
-	CommitHash  string
-	IsRemote    bool // 是否为远程标签
+	Name        string `json:"name"`
+	Checked     bool   `json:"checked"`
+	CreatedTime string `json:"created_time"`
+	Message     string `json:"message"`
+	CommitHash  string `json:"commit_hash"`
+	IsRemote    bool   `json:"is_remote"` // 是否为远程标签
 }
 
 // BranchInfo 存储分支信息
 type BranchInfo struct {
-	Name       string
-	Checked    bool
-	IsRemote   bool
-	LastCommit string
-	CommitHash string
-	CommitTime string
+	Name       string `json:"name"`
+	Checked    bool   `json:"checked"`
+	IsRemote   bool   `json:"is_remote"`
+	LastCommit string `json:"last_commit"`
+	CommitHash string `json:"commit_hash"`
+	CommitTime string `json:"commit_time"`
+}
+
+// WorkingTreeStatus 工作区中未提交的修改、未跟踪的文件和 stash
+type WorkingTreeStatus struct {
+	Modified       int      `json:"modified"`        // 有修改的已跟踪文件数量
+	Untracked      int      `json:"untracked"`       // 未跟踪的文件数量
+	Stashes        int      `json:"stashes"`         // stash 数量
+	ModifiedFiles  []string `json:"modified_files"`  // 有修改的文件，最多列出 20 个
+	UntrackedFiles []string `json:"untracked_files"` // 未跟踪的文件，最多列出 20 个
+}
+
+// Dirty 是否有未提交的修改，未跟踪的文件不影响检出
+func (s WorkingTreeStatus) Dirty() bool {
+	return s.Modified > 0
+}
+
+// workingTreeStatus 将工作区状态转换为页面显示的信息
+func workingTreeStatus(status GitStatus) WorkingTreeStatus {
+	const maxFiles = 20
+	info := WorkingTreeStatus{
+		Modified:       len(status.Modified),
+		Untracked:      len(status.Untracked),
+		Stashes:        status.Stashes,
+		ModifiedFiles:  status.Modified,
+		UntrackedFiles: status.Untracked,
+	}
+	if len(info.ModifiedFiles) > maxFiles {
+		info.ModifiedFiles = info.ModifiedFiles[:maxFiles]
+	}
+	if len(info.UntrackedFiles) > maxFiles {
+		info.UntrackedFiles = info.UntrackedFiles[:maxFiles]
+	}
+	return info
 }
 
 // ProjectInfo 项目信息
 type ProjectInfo struct {
-	Name          string
-	Path          string
-	Description   string
-	Tags          []TagInfo
-	Branches      []BranchInfo
-	Current       bool
-	CurrentBranch string // 当前分支名
-	CurrentTag    string // 当前标签名
-	WorkingMode   string // "branch" 或 "tag"
+	Name          string            `json:"name"`
+	Path          string            `json:"path"`
+	Description   string            `json:"description"`
+	Group         string            `json:"group"`
+	Environment   string            `json:"environment"`
+	Production    bool              `json:"production"` // 生产环境，检出和回退需要输入项目名称确认
+	Tags          []TagInfo         `json:"tags"`
+	Branches      []BranchInfo      `json:"branches"`
+	Current       bool              `json:"-"`
+	CurrentBranch string            `json:"current_branch"`        // 当前分支名
+	CurrentTag    string            `json:"current_tag"`           // 当前标签名
+	WorkingMode   string            `json:"working_mode"`          // "branch"、"tag"、"detached" 或 "unknown"
+	CurrentCommit string            `json:"current_commit"`        // 当前短提交哈希
+	PreviousRef   string            `json:"previous_ref"`          // 上一次部署前的版本，可用于一键回退
+	DirtyPolicy   string            `json:"dirty_policy"`          // 工作区有未提交修改时的检出策略
+	WorkingTree   WorkingTreeStatus `json:"working_tree"`          // 工作区状态
+	Unavailable   string            `json:"unavailable,omitempty"` // 项目不可用的原因，如路径不存在（目录未挂载）
+}
+
+// ProjectGroup 首页按分组显示的项目
+type ProjectGroup struct {
+	Name     string // 为空表示未分组
+	Projects []ProjectInfo
 }
 
 // VersionController 版本控制器
@@ -98,7 +144,8 @@ func getProjectFromCache(projectPath string) (ProjectInfo, bool) {
 	defer cacheMutex.RUnlock()
 
 	cache, exists := projectCache[projectPath]
-	if !exists || time.Since(cache.UpdateTime) >= cacheExpiry {
+	// 异步更新时创建的占位缓存项没有项目信息，不能作为命中
+	if !exists || cache.ProjectInfo.Name == "" || time.Since(cache.UpdateTime) >= cacheExpiry {
 		return ProjectInfo{}, false
 	}
 
@@ -117,6 +164,24 @@ func setProjectCache(projectPath string, projectInfo ProjectInfo) {
 	}
 }
 
+// setProjectCacheIfUnchanged 缓存在 since 之后没有被其他刷新更新或删除时写入，返回是否已写入
+// 避免检出完成后刷新的缓存被更早开始的后台刷新覆盖
+func setProjectCacheIfUnchanged(projectPath string, projectInfo ProjectInfo, since time.Time) bool {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	cache, exists := projectCache[projectPath]
+	if !exists || cache.UpdateTime.After(since) {
+		return false
+	}
+	projectCache[projectPath] = &ProjectCacheItem{
+		ProjectInfo: projectInfo,
+		UpdateTime:  time.Now(),
+		Updating:    false,
+	}
+	return true
+}
+
 // markProjectUpdating 标记项目正在更新
 func markProjectUpdating(projectPath string, updating bool) {
 	cacheMutex.Lock()
@@ -151,13 +216,30 @@ func (c *VersionController) updateProjectAsync(project models.Project) {
 		markProjectUpdating(project.Path, true)
 		defer markProjectUpdating(project.Path, false)
 
+		// 项目正在执行其他操作时跳过，操作完成后会刷新缓存
+		// fetch 会修改仓库的引用，获取信息期间一直持有项目锁，避免与检出中的 fetch/pull 同时运行
+		unlock, err := tryLockProject(project, "后台刷新", "系统")
+		if err != nil {
+			if DebugMode {
+				fmt.Printf("⚠️ 跳过异步更新项目 %s: %v\n", project.Name, err)
+			}
+			return
+		}
+		defer unlock()
+
 		if DebugMode {
 			fmt.Printf("🔄 异步更新项目: %s\n", project.Name)
 		}
 
-		// 获取完整的项目信息
-		projectInfo := c.buildProjectInfo(project, false) // false = 完整模式
-		setProjectCache(project.Path, projectInfo)
+		// 获取完整的项目信息，后台更新不随触发它的请求结束而取消
+		start := time.Now()
+		projectInfo := c.buildProjectInfo(context.Background(), project, false) // false = 完整模式
+		if !setProjectCacheIfUnchanged(project.Path, projectInfo, start) {
+			if DebugMode {
+				fmt.Printf("⚠️ 项目 %s 在异步更新期间已被刷新，丢弃结果\n", project.Name)
+			}
+			return
+		}
 
 		if DebugMode {
 			fmt.Printf("✅ 异步更新完成: %s\n", project.Name)
@@ -219,200 +301,104 @@ func compareVersions(v1, v2 string) int {
 	return 0
 }
 
-// getTagDetails 获取标签的详细信息
-func (c *VersionController) getTagDetails(projectPath, tagName string) (string, string, string) {
-	// 获取标签创建时间
-	createdTime := "未知时间"
-	if timeStr, err := c.executeGitCommand(projectPath, "log", "-1", "--format=%ci", tagName); err == nil {
-		if timeStr != "" {
-			if t, err := time.Parse("2006-01-02 15:04:05 -0700", timeStr); err == nil {
-				createdTime = t.Format("2006-01-02 15:04")
-			}
-		}
-	} else if DebugMode {
-		fmt.Printf("⚠️ 获取标签 %s 时间失败: %v\n", tagName, err)
+// tagInfo 将标签转换为页面显示的标签信息
+func tagInfo(ref GitRef) TagInfo {
+	info := TagInfo{
+		Name:        ref.Name,
+		CreatedTime: "未知时间",
+		Message:     "无备注",
+		CommitHash:  shortHash(ref.Commit),
 	}
-
-	// 获取标签消息
-	message := "无备注"
-	if msg, err := c.executeGitCommand(projectPath, "tag", "-l", "--format=%(contents)", tagName); err == nil {
-		if msg != "" {
-			message = msg
-		}
-	} else if DebugMode {
-		fmt.Printf("⚠️ 获取标签 %s 消息失败: %v\n", tagName, err)
+	if !ref.Time.IsZero() {
+		info.CreatedTime = ref.Time.Format("2006-01-02 15:04")
 	}
-
-	// 获取提交hash
-	commitHash := ""
-	if hash, err := c.executeGitCommand(projectPath, "rev-list", "-n", "1", tagName); err == nil {
-		if len(hash) >= 7 {
-			commitHash = hash[:7]
-		}
-	} else if DebugMode {
-		fmt.Printf("⚠️ 获取标签 %s 哈希失败: %v\n", tagName, err)
+	if ref.Message != "" {
+		info.Message = ref.Message
 	}
-
-	return createdTime, message, commitHash
+	return info
 }
 
-// getCurrentWorkingMode 获取当前工作模式和状态
-func (c *VersionController) getCurrentWorkingMode(projectPath string) (string, string, string) {
-	if DebugMode {
-		fmt.Printf("🔍 获取项目 %s 的当前工作模式...\n", projectPath)
+// branchInfo 将分支转换为页面显示的分支信息
+func branchInfo(ref GitRef) BranchInfo {
+	info := BranchInfo{
+		Name:       ref.Name,
+		IsRemote:   ref.Remote,
+		CommitHash: shortHash(ref.Commit),
+		LastCommit: ref.Message,
 	}
-
-	// 检查是否在分支上
-	if branchName, err := c.executeGitCommand(projectPath, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
-		branchName = strings.TrimSpace(branchName)
-		if branchName != "HEAD" && branchName != "" {
-			if DebugMode {
-				fmt.Printf("✅ 当前在分支: %s\n", branchName)
-			}
-			return "branch", branchName, ""
-		}
+	if !ref.Time.IsZero() {
+		info.CommitTime = ref.Time.Format("2006-01-02 15:04")
 	}
-
-	// 检查是否在标签上
-	if tagName, err := c.executeGitCommand(projectPath, "describe", "--exact-match", "--tags"); err == nil {
-		tagName = strings.TrimSpace(tagName)
-		if tagName != "" {
-			if DebugMode {
-				fmt.Printf("✅ 当前在标签: %s\n", tagName)
-			}
-			return "tag", "", tagName
-		}
-	}
-
-	// 尝试获取最近的标签
-	if tagName, err := c.executeGitCommand(projectPath, "describe", "--tags"); err == nil {
-		tagName = strings.TrimSpace(tagName)
-		if tagName != "" {
-			if DebugMode {
-				fmt.Printf("⚠️ 当前在游离状态，最近标签: %s\n", tagName)
-			}
-			return "detached", "", tagName
-		}
+	if len(info.LastCommit) > 50 {
+		info.LastCommit = info.LastCommit[:50] + "..."
 	}
-
-	if DebugMode {
-		fmt.Printf("⚠️ 无法确定当前工作模式\n")
-	}
-	return "unknown", "", ""
+	return info
 }
 
-// getBranches 获取所有分支信息
-func (c *VersionController) getBranches(projectPath string) ([]BranchInfo, error) {
-	if DebugMode {
-		fmt.Printf("🔍 正在获取项目 %s 的分支信息...\n", projectPath)
-	}
-
-	// 先执行 fetch 获取最新的远程分支信息
-	if _, err := c.executeGitCommand(projectPath, "fetch", "--all"); err != nil {
+// currentWorkingMode 根据 HEAD 状态返回工作模式、分支名、标签名
+// 游离状态时标签名为最近标签描述或短提交哈希
+func currentWorkingMode(head GitHead) (string, string, string) {
+	switch {
+	case head.Branch != "":
 		if DebugMode {
-			fmt.Printf("⚠️ fetch 远程分支失败: %v\n", err)
-		}
-	}
-
-	// 获取所有分支（本地和远程）
-	branchOutput, err := c.executeGitCommand(projectPath, "branch", "-a", "-v")
-	if err != nil {
-		return nil, fmt.Errorf("获取分支列表失败: %v", err)
-	}
-
-	// 获取当前分支
-	currentBranch := ""
-	if branch, err := c.executeGitCommand(projectPath, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
-		currentBranch = branch
-	}
-
-	var branches []BranchInfo
-	lines := strings.Split(branchOutput, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		// 解析分支信息
-		var branchInfo BranchInfo
-
-		// 检查是否为当前分支
-		if strings.HasPrefix(line, "* ") {
-			branchInfo.Checked = true
-			line = strings.TrimPrefix(line, "* ")
-		} else if strings.HasPrefix(line, "  ") {
-			line = strings.TrimPrefix(line, "  ")
-		}
-
-		// 分割分支名和提交信息
-		parts := strings.Fields(line)
-		if len(parts) < 2 {
-			continue
+			fmt.Printf("✅ 当前在分支: %s\n", head.Branch)
 		}
-
-		branchName := parts[0]
-		commitHash := parts[1]
-
-		// 检查是否为远程分支
-		if strings.HasPrefix(branchName, "remotes/") {
-			branchInfo.IsRemote = true
-			// 去掉 remotes/ 前缀但保留 origin/ 等
-			branchName = strings.TrimPrefix(branchName, "remotes/")
-		}
-
-		// 跳过 HEAD 指针
-		if strings.Contains(branchName, "HEAD ->") {
-			continue
-		}
-
-		branchInfo.Name = branchName
-		branchInfo.CommitHash = commitHash
-
-		// 获取最后一次提交的时间和信息
-		if commitTime, err := c.executeGitCommand(projectPath, "log", "-1", "--format=%ci", commitHash); err == nil {
-			if t, err := time.Parse("2006-01-02 15:04:05 -0700", commitTime); err == nil {
-				branchInfo.CommitTime = t.Format("2006-01-02 15:04")
-			}
+		return "branch", head.Branch, ""
+	case head.Tag != "":
+		if DebugMode {
+			fmt.Printf("✅ 当前在标签: %s\n", head.Tag)
 		}
-
-		if commitMsg, err := c.executeGitCommand(projectPath, "log", "-1", "--format=%s", commitHash); err == nil {
-			if len(commitMsg) > 50 {
-				commitMsg = commitMsg[:50] + "..."
-			}
-			branchInfo.LastCommit = commitMsg
+		return "tag", "", head.Tag
+	case head.Describe != "":
+		if DebugMode {
+			fmt.Printf("⚠️ 当前在游离状态，最近标签: %s\n", head.Describe)
 		}
-
-		// 设置当前分支标记
-		if branchName == currentBranch || (branchInfo.IsRemote && strings.HasSuffix(branchName, "/"+currentBranch)) {
-			branchInfo.Checked = true
+		return "detached", "", head.Describe
+	case head.Commit != "":
+		// 没有任何可描述的标签时，使用提交哈希表示游离状态
+		if DebugMode {
+			fmt.Printf("⚠️ 当前在游离状态，提交: %s\n", shortHash(head.Commit))
 		}
-
-		branches = append(branches, branchInfo)
+		return "detached", "", shortHash(head.Commit)
 	}
 
 	if DebugMode {
-		fmt.Printf("✅ 获取到 %d 个分支\n", len(branches))
+		fmt.Printf("⚠️ 无法确定当前工作模式\n")
 	}
-
-	return branches, nil
+	return "unknown", "", ""
 }
 
 // buildProjectInfo 构建项目信息（支持快速模式和完整模式）
-func (c *VersionController) buildProjectInfo(project models.Project, fastMode bool) ProjectInfo {
+func (c *VersionController) buildProjectInfo(ctx context.Context, project models.Project, fastMode bool) ProjectInfo {
 	projectInfo := ProjectInfo{
 		Name:        project.Name,
 		Path:        project.Path,
 		Description: project.Description,
 		Current:     false, // 稍后在调用处设置
 	}
+	// 目录不存在（如尚未挂载）时标记为不可用，不执行 git 命令
+	if err := project.PathError(); err != nil {
+		projectInfo.Unavailable = err.Error()
+		projectInfo.WorkingMode = "unknown"
+		return projectInfo
+	}
+	backend := gitBackendFor(project)
 
 	// 获取当前工作模式和状态
-	workingMode, currentBranch, currentTag := c.getCurrentWorkingMode(project.Path)
+	head, err := backend.Head(ctx, project.Path)
+	if err != nil && DebugMode {
+		fmt.Printf("⚠️ 获取项目 %s 的当前状态失败: %v\n", project.Name, err)
+	}
+	workingMode, currentBranch, currentTag := currentWorkingMode(head)
 	projectInfo.WorkingMode = workingMode
 	projectInfo.CurrentBranch = currentBranch
 	projectInfo.CurrentTag = currentTag
+	projectInfo.CurrentCommit = shortHash(head.Commit)
+	if status, err := backend.Status(ctx, project.Path); err == nil {
+		projectInfo.WorkingTree = workingTreeStatus(status)
+	} else if DebugMode {
+		fmt.Printf("⚠️ 获取项目 %s 的工作区状态失败: %v\n", project.Name, err)
+	}
 
 	if fastMode {
 		// 快速模式：只获取基本信息，不获取详细标签和分支信息
@@ -425,9 +411,12 @@ func (c *VersionController) buildProjectInfo(project models.Project, fastMode bo
 		return projectInfo
 	}
 
-	// 完整模式：获取详细信息
-	tags, _ := c.getTagsFast(project.Path)
-	branches, _ := c.getBranchesFast(project.Path)
+	// 完整模式：获取详细信息，只在必要时才 fetch
+	if !SkipFetch {
+		c.fetchForListing(ctx, backend, project.Path)
+	}
+	tags, _ := c.getTagsFast(ctx, backend, project.Path, workingMode, currentTag)
+	branches, _ := c.getBranchesFast(ctx, backend, project.Path, workingMode, currentBranch)
 
 	projectInfo.Tags = tags
 	projectInfo.Branches = branches
@@ -447,81 +436,24 @@ func (c *VersionController) buildProjectInfo(project models.Project, fastMode bo
 	return projectInfo
 }
 
-// getTagsFast 快速获取标签信息（减少 fetch 调用）
-func (c *VersionController) getTagsFast(projectPath string) ([]TagInfo, error) {
+// getTagsFast 快速获取标签信息，并根据当前工作模式设置选中标签
+func (c *VersionController) getTagsFast(ctx context.Context, backend GitBackend, projectPath, workingMode, currentTag string) ([]TagInfo, error) {
 	if DebugMode {
 		fmt.Printf("🔍 正在快速获取项目 %s 的标签...\n", projectPath)
 	}
 
-	// 在快速模式下，只在必要时才 fetch
-	if !SkipFetch {
-		// 使用超时的 fetch，避免长时间等待
-		done := make(chan bool, 1)
-		go func() {
-			c.executeGitCommand(projectPath, "fetch", "--tags")
-			done <- true
-		}()
-
-		select {
-		case <-done:
-			// fetch 完成
-		case <-time.After(3 * time.Second):
-			// 超时，继续使用本地标签
-			if DebugMode {
-				fmt.Printf("⚠️ fetch 超时，使用本地标签\n")
-			}
-		}
-	}
-
-	// 获取所有标签（本地优先）
-	tagOutput, err := c.executeGitCommand(projectPath, "tag", "-l", "--sort=-version:refname")
+	refs, err := backend.Tags(ctx, projectPath)
 	if err != nil {
-		return nil, fmt.Errorf("获取标签列表失败: %v", err)
+		return nil, err
 	}
 
-	tags := strings.Split(tagOutput, "\n")
 	var tagInfos []TagInfo
+	for _, ref := range refs {
+		info := tagInfo(ref)
 
-	// 获取当前状态以设置选中标签
-	workingMode, _, currentTag := c.getCurrentWorkingMode(projectPath)
-
-	// 限制处理的标签数量以提高性能
-	maxTags := 20
-	count := 0
-
-	for _, tag := range tags {
-		tag = strings.TrimSpace(tag)
-		if tag != "" {
-			count++
-			if count > maxTags {
-				// 只处理前20个标签以提高性能
-				break
-			}
-
-			// 在快速模式下简化标签详细信息获取
-			var createdTime, message, commitHash string
-			if FastMode {
-				// 快速模式：只获取基本信息
-				createdTime = "N/A"
-				message = "使用快速模式"
-				commitHash = "N/A"
-			} else {
-				// 完整模式：获取详细信息
-				createdTime, message, commitHash = c.getTagDetails(projectPath, tag)
-			}
-
-			// 确保标签选中状态的正确性
-			isChecked := (workingMode == "tag" || workingMode == "detached") && tag == currentTag
-
-			tagInfos = append(tagInfos, TagInfo{
-				Name:        tag,
-				Checked:     isChecked,
-				CreatedTime: createdTime,
-				Message:     message,
-				CommitHash:  commitHash,
-				IsRemote:    false,
-			})
-		}
+		// 确保标签选中状态的正确性
+		info.Checked = (workingMode == "tag" || workingMode == "detached") && ref.Name == currentTag
+		tagInfos = append(tagInfos, info)
 	}
 
 	if DebugMode {
@@ -531,119 +463,37 @@ func (c *VersionController) getTagsFast(projectPath string) ([]TagInfo, error) {
 	return tagInfos, nil
 }
 
-// getBranchesFast 快速获取分支信息（减少 fetch 调用）
-func (c *VersionController) getBranchesFast(projectPath string) ([]BranchInfo, error) {
-	if DebugMode {
-		fmt.Printf("🔍 正在快速获取项目 %s 的分支信息...\n", projectPath)
+// fetchForListing 获取标签/分支列表前执行 fetch，超过 GitListFetchTimeout 时终止 fetch 并使用本地数据
+func (c *VersionController) fetchForListing(ctx context.Context, backend GitBackend, projectPath string) {
+	ctx, cancel := context.WithTimeout(ctx, GitListFetchTimeout)
+	defer cancel()
+
+	if err := backend.Fetch(ctx, projectPath); err != nil && DebugMode {
+		fmt.Printf("⚠️ fetch 失败，使用本地数据: %v\n", err)
 	}
+}
 
-	// 在快速模式下，只在必要时才 fetch
-	if !SkipFetch {
-		// 使用超时的 fetch，避免长时间等待
-		done := make(chan bool, 1)
-		go func() {
-			c.executeGitCommand(projectPath, "fetch", "--all")
-			done <- true
-		}()
-
-		select {
-		case <-done:
-			// fetch 完成
-		case <-time.After(3 * time.Second):
-			// 超时，继续使用本地分支
-			if DebugMode {
-				fmt.Printf("⚠️ fetch 分支超时，使用本地分支\n")
-			}
-		}
+// getBranchesFast 快速获取分支信息，并根据当前工作模式设置选中分支
+func (c *VersionController) getBranchesFast(ctx context.Context, backend GitBackend, projectPath, workingMode, currentBranch string) ([]BranchInfo, error) {
+	if DebugMode {
+		fmt.Printf("🔍 正在快速获取项目 %s 的分支信息...\n", projectPath)
 	}
 
-	// 获取分支信息（本地和远程）
-	branchOutput, err := c.executeGitCommand(projectPath, "branch", "-a", "-v")
+	refs, err := backend.Branches(ctx, projectPath)
 	if err != nil {
-		return nil, fmt.Errorf("获取分支列表失败: %v", err)
+		return nil, err
 	}
 
-	// 获取当前分支和工作模式
-	workingMode, currentBranch, _ := c.getCurrentWorkingMode(projectPath)
-
 	var branches []BranchInfo
-	lines := strings.Split(branchOutput, "\n")
-
-	// 限制处理的分支数量以提高性能
-	maxBranches := 15
-	count := 0
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		count++
-		if count > maxBranches {
-			break
-		}
-
-		// 解析分支信息
-		var branchInfo BranchInfo
-
-		// 检查是否为当前分支
-		if strings.HasPrefix(line, "* ") {
-			branchInfo.Checked = true
-			line = strings.TrimPrefix(line, "* ")
-		} else if strings.HasPrefix(line, "  ") {
-			line = strings.TrimPrefix(line, "  ")
-		}
-
-		// 分割分支名和提交信息
-		parts := strings.Fields(line)
-		if len(parts) < 2 {
-			continue
-		}
-
-		branchName := parts[0]
-		commitHash := parts[1]
-
-		// 检查是否为远程分支
-		if strings.HasPrefix(branchName, "remotes/") {
-			branchInfo.IsRemote = true
-			branchName = strings.TrimPrefix(branchName, "remotes/")
-		}
-
-		// 跳过 HEAD 指针
-		if strings.Contains(branchName, "HEAD ->") {
-			continue
-		}
-
-		branchInfo.Name = branchName
-		branchInfo.CommitHash = commitHash
-
-		// 在快速模式下简化提交信息获取
-		if FastMode {
-			branchInfo.CommitTime = "N/A"
-			branchInfo.LastCommit = "使用快速模式"
-		} else {
-			// 获取最后一次提交的时间和信息
-			if commitTime, err := c.executeGitCommand(projectPath, "log", "-1", "--format=%ci", commitHash); err == nil {
-				if t, err := time.Parse("2006-01-02 15:04:05 -0700", commitTime); err == nil {
-					branchInfo.CommitTime = t.Format("2006-01-02 15:04")
-				}
-			}
-
-			if commitMsg, err := c.executeGitCommand(projectPath, "log", "-1", "--format=%s", commitHash); err == nil {
-				if len(commitMsg) > 50 {
-					commitMsg = commitMsg[:50] + "..."
-				}
-				branchInfo.LastCommit = commitMsg
-			}
-		}
+	for _, ref := range refs {
+		info := branchInfo(ref)
 
 		// 设置当前分支标记 - 只有在分支模式下才标记分支为选中
-		if workingMode == "branch" && (branchName == currentBranch || (branchInfo.IsRemote && strings.HasSuffix(branchName, "/"+currentBranch))) {
-			branchInfo.Checked = true
+		if workingMode == "branch" && (ref.Name == currentBranch || (ref.Remote && strings.HasSuffix(ref.Name, "/"+currentBranch))) {
+			info.Checked = true
 		}
 
-		branches = append(branches, branchInfo)
+		branches = append(branches, info)
 	}
 
 	if DebugMode {
@@ -654,7 +504,7 @@ func (c *VersionController) getBranchesFast(projectPath string) ([]BranchInfo, e
 }
 
 // debugProjectInfo 输出项目的调试信息
-func (c *VersionController) debugProjectInfo(project models.Project) {
+func (c *VersionController) debugProjectInfo(ctx context.Context, project models.Project) {
 	fmt.Printf("\n🔧 项目诊断信息:\n")
 	fmt.Printf("   名称: %s\n", project.Name)
 	fmt.Printf("   路径: %s\n", project.Path)
@@ -683,17 +533,21 @@ func (c *VersionController) debugProjectInfo(project models.Project) {
 		return
 	}
 
-	// 测试 git 命令
-	if _, err := c.executeGitCommand(project.Path, "status", "--porcelain"); err == nil {
-		fmt.Printf("   Git 命令: ✅ 正常\n")
+	// 测试 Git 后端
+	backend := gitBackendFor(project)
+	backendName := project.GitBackend
+	if backendName == "" {
+		backendName = models.GitBackendExec
+	}
+	if _, err := backend.Head(ctx, project.Path); err == nil {
+		fmt.Printf("   Git 后端: ✅ 正常 (%s)\n", backendName)
 	} else {
-		fmt.Printf("   Git 命令: ❌ 失败 (%v)\n", err)
+		fmt.Printf("   Git 后端: ❌ 失败 (%s: %v)\n", backendName, err)
 	}
 
-	// 快速获取标签数量
-	if tagOutput, err := c.executeGitCommand(project.Path, "tag", "-l"); err == nil {
-		tagCount := len(strings.Fields(tagOutput))
-		fmt.Printf("   标签数量: %d\n", tagCount)
+	// 获取标签数量
+	if tags, err := backend.Tags(ctx, project.Path); err == nil {
+		fmt.Printf("   标签数量: %d\n", len(tags))
 	} else {
 		fmt.Printf("   标签获取: ❌ 失败 (%v)\n", err)
 	}
@@ -701,13 +555,13 @@ func (c *VersionController) debugProjectInfo(project models.Project) {
 }
 
 // fixGitOwnership 修复 Git 仓库权限问题
-func (c *VersionController) fixGitOwnership(projectPath string) error {
+func (c *VersionController) fixGitOwnership(ctx context.Context, projectPath string) error {
 	if DebugMode {
 		fmt.Printf("🔧 尝试修复 Git 权限: %s\n", projectPath)
 	}
 
 	// 方法1: 尝试添加全局安全目录配置
-	if err := c.tryGlobalSafeDirectory(projectPath); err == nil {
+	if err := c.tryGlobalSafeDirectory(ctx, projectPath); err == nil {
 		if DebugMode {
 			fmt.Printf("✅ 全局配置成功\n")
 		}
@@ -715,7 +569,7 @@ func (c *VersionController) fixGitOwnership(projectPath string) error {
 	}
 
 	// 方法2: 尝试添加系统级安全目录配置
-	if err := c.trySystemSafeDirectory(projectPath); err == nil {
+	if err := c.trySystemSafeDirectory(ctx, projectPath); err == nil {
 		if DebugMode {
 			fmt.Printf("✅ 系统配置成功\n")
 		}
@@ -723,7 +577,7 @@ func (c *VersionController) fixGitOwnership(projectPath string) error {
 	}
 
 	// 方法3: 尝试本地仓库配置
-	if err := c.tryLocalSafeDirectory(projectPath); err == nil {
+	if err := c.tryLocalSafeDirectory(ctx, projectPath); err == nil {
 		if DebugMode {
 			fmt.Printf("✅ 本地配置成功\n")
 		}
@@ -731,7 +585,7 @@ func (c *VersionController) fixGitOwnership(projectPath string) error {
 	}
 
 	// 方法4: 设置 HOME 环境变量后重试
-	if err := c.tryWithHomeSet(projectPath); err == nil {
+	if err := c.tryWithHomeSet(ctx, projectPath); err == nil {
 		if DebugMode {
 			fmt.Printf("✅ 设置 HOME 后成功\n")
 		}
@@ -742,14 +596,10 @@ func (c *VersionController) fixGitOwnership(projectPath string) error {
 }
 
 // tryGlobalSafeDirectory 尝试添加全局安全目录
-func (c *VersionController) tryGlobalSafeDirectory(projectPath string) error {
-	cmd := exec.Command("git", "config", "--global", "--add", "safe.directory", projectPath)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
+func (c *VersionController) tryGlobalSafeDirectory(ctx context.Context, projectPath string) error {
+	if _, err := runGit(ctx, "", nil, "config", "--global", "--add", "safe.directory", projectPath); err != nil {
 		if DebugMode {
-			fmt.Printf("⚠️ 全局配置失败: %v, 输出: %s\n", err, stderr.String())
+			fmt.Printf("⚠️ 全局配置失败: %v\n", err)
 		}
 		return err
 	}
@@ -757,14 +607,10 @@ func (c *VersionController) tryGlobalSafeDirectory(projectPath string) error {
 }
 
 // trySystemSafeDirectory 尝试添加系统级安全目录
-func (c *VersionController) trySystemSafeDirectory(projectPath string) error {
-	cmd := exec.Command("git", "config", "--system", "--add", "safe.directory", projectPath)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
+func (c *VersionController) trySystemSafeDirectory(ctx context.Context, projectPath string) error {
+	if _, err := runGit(ctx, "", nil, "config", "--system", "--add", "safe.directory", projectPath); err != nil {
 		if DebugMode {
-			fmt.Printf("⚠️ 系统配置失败: %v, 输出: %s\n", err, stderr.String())
+			fmt.Printf("⚠️ 系统配置失败: %v\n", err)
 		}
 		return err
 	}
@@ -772,15 +618,10 @@ func (c *VersionController) trySystemSafeDirectory(projectPath string) error {
 }
 
 // tryLocalSafeDirectory 尝试在本地仓库配置
-func (c *VersionController) tryLocalSafeDirectory(projectPath string) error {
-	cmd := exec.Command("git", "config", "--add", "safe.directory", projectPath)
-	cmd.Dir = projectPath
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
+func (c *VersionController) tryLocalSafeDirectory(ctx context.Context, projectPath string) error {
+	if _, err := runGit(ctx, projectPath, nil, "config", "--add", "safe.directory", projectPath); err != nil {
 		if DebugMode {
-			fmt.Printf("⚠️ 本地配置失败: %v, 输出: %s\n", err, stderr.String())
+			fmt.Printf("⚠️ 本地配置失败: %v\n", err)
 		}
 		return err
 	}
@@ -788,21 +629,17 @@ func (c *VersionController) tryLocalSafeDirectory(projectPath string) error {
 }
 
 // tryWithHomeSet 设置 HOME 环境变量后重试
-func (c *VersionController) tryWithHomeSet(projectPath string) error {
+func (c *VersionController) tryWithHomeSet(ctx context.Context, projectPath string) error {
 	// 尝试设置一个临时的 HOME 目录
 	tmpHome := "/tmp"
 	if _, err := os.Stat("/tmp"); os.IsNotExist(err) {
 		tmpHome = "."
 	}
 
-	cmd := exec.Command("git", "config", "--global", "--add", "safe.directory", projectPath)
-	cmd.Env = append(os.Environ(), "HOME="+tmpHome)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
+	env := append(os.Environ(), "HOME="+tmpHome)
+	if _, err := runGit(ctx, "", env, "config", "--global", "--add", "safe.directory", projectPath); err != nil {
 		if DebugMode {
-			fmt.Printf("⚠️ 设置 HOME 后配置失败: %v, 输出: %s\n", err, stderr.String())
+			fmt.Printf("⚠️ 设置 HOME 后配置失败: %v\n", err)
 		}
 		return err
 	}
@@ -810,9 +647,9 @@ func (c *VersionController) tryWithHomeSet(projectPath string) error {
 }
 
 // executeGitCommand 执行 Git 命令的通用方法，自动处理权限问题
-func (c *VersionController) executeGitCommand(projectPath string, args ...string) (string, error) {
+func (c *VersionController) executeGitCommand(ctx context.Context, projectPath string, args ...string) (string, error) {
 	// 方法1: 直接尝试执行命令
-	output, err := c.tryGitCommand(projectPath, args...)
+	output, err := c.tryGitCommand(ctx, projectPath, args...)
 	if err == nil {
 		return output, nil
 	}
@@ -827,7 +664,7 @@ func (c *VersionController) executeGitCommand(projectPath string, args ...string
 	}
 
 	// 方法2: 使用环境变量绕过权限检查
-	output, err = c.tryGitCommandWithEnvBypass(projectPath, args...)
+	output, err = c.tryGitCommandWithEnvBypass(ctx, projectPath, args...)
 	if err == nil {
 		if DebugMode {
 			fmt.Printf("✅ 环境变量绕过成功\n")
@@ -836,12 +673,12 @@ func (c *VersionController) executeGitCommand(projectPath string, args ...string
 	}
 
 	// 方法3: 尝试修复权限后重试
-	if fixErr := c.fixGitOwnership(projectPath); fixErr != nil {
+	if fixErr := c.fixGitOwnership(ctx, projectPath); fixErr != nil {
 		return "", fmt.Errorf("权限修复失败: %v", fixErr)
 	}
 
 	// 重试命令
-	output, err = c.tryGitCommand(projectPath, args...)
+	output, err = c.tryGitCommand(ctx, projectPath, args...)
 	if err != nil {
 		return "", fmt.Errorf("修复权限后仍然失败: %v", err)
 	}
@@ -850,27 +687,12 @@ func (c *VersionController) executeGitCommand(projectPath string, args ...string
 }
 
 // tryGitCommand 尝试执行 Git 命令
-func (c *VersionController) tryGitCommand(projectPath string, args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = projectPath
-
-	var out bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("命令执行失败: %v, 输出: %s", err, stderr.String())
-	}
-
-	return strings.TrimSpace(out.String()), nil
+func (c *VersionController) tryGitCommand(ctx context.Context, projectPath string, args ...string) (string, error) {
+	return runGit(ctx, projectPath, nil, args...)
 }
 
 // tryGitCommandWithEnvBypass 使用环境变量绕过权限检查
-func (c *VersionController) tryGitCommandWithEnvBypass(projectPath string, args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = projectPath
-
+func (c *VersionController) tryGitCommandWithEnvBypass(ctx context.Context, projectPath string, args ...string) (string, error) {
 	// 设置环境变量绕过权限检查
 	env := os.Environ()
 
@@ -895,78 +717,27 @@ func (c *VersionController) tryGitCommandWithEnvBypass(projectPath string, args
 		env = append(env, "HOME=/tmp")
 	}
 
-	cmd.Env = env
-
-	var out bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		if DebugMode {
-			fmt.Printf("⚠️ 环境变量绕过失败: %v, 输出: %s\n", err, stderr.String())
-		}
-		return "", fmt.Errorf("环境变量绕过失败: %v, 输出: %s", err, stderr.String())
-	}
-
-	return strings.TrimSpace(out.String()), nil
-}
-
-// getTags 获取指定项目的所有Git标签（包括远程标签）
-func (c *VersionController) getTags(projectPath string) ([]TagInfo, error) {
-	// 首先检查目录是否存在
-	if _, err := os.Stat(projectPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("项目路径 %s 不存在", projectPath)
-	}
-
-	// 检查是否是 Git 仓库
-	gitDir := filepath.Join(projectPath, ".git")
-	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("目录 %s 不是一个 Git 仓库（缺少 .git 目录）", projectPath)
-	}
-
-	if DebugMode {
-		fmt.Printf("🔍 正在获取项目 %s 的 Git 标签（包括远程）...\n", projectPath)
-	}
-
-	// 使用快速方法获取标签
-	tags, err := c.getTagsFast(projectPath)
+	output, err := runGit(ctx, projectPath, env, args...)
 	if err != nil {
 		if DebugMode {
-			fmt.Printf("⚠️ 获取标签失败: %v\n", err)
+			fmt.Printf("⚠️ 环境变量绕过失败: %v\n", err)
 		}
-		return []TagInfo{}, err
+		return "", fmt.Errorf("环境变量绕过失败: %v", err)
 	}
-
-	// 按版本号排序（降序，最新版本在前）
-	sort.Slice(tags, func(i, j int) bool {
-		return compareVersions(tags[i].Name, tags[j].Name) > 0
-	})
-
-	// 获取当前状态用于调试
-	workingMode, currentBranch, currentTag := c.getCurrentWorkingMode(projectPath)
-	if DebugMode {
-		fmt.Printf("✅ 成功获取项目 %s 的标签信息，当前模式: %s", projectPath, workingMode)
-		if workingMode == "branch" {
-			fmt.Printf("，当前分支: %s", currentBranch)
-		} else if workingMode == "tag" {
-			fmt.Printf("，当前标签: %s", currentTag)
-		}
-		fmt.Printf("\n")
-	}
-
-	return tags, nil
+	return output, nil
 }
 
 // checkoutTag 检出指定标签（回滚功能）
-func (c *VersionController) checkoutTag(projectPath, tag string) error {
+func (c *VersionController) checkoutTag(ctx context.Context, project models.Project, tag string) error {
+	backend := gitBackendFor(project)
+
 	// 先获取最新代码和标签
-	if _, err := c.executeGitCommand(projectPath, "fetch", "--tags"); err != nil {
+	if err := backend.Fetch(ctx, project.Path); err != nil {
 		return fmt.Errorf("git fetch tags failed: %v", err)
 	}
 
 	// 检出指定标签
-	if _, err := c.executeGitCommand(projectPath, "checkout", tag); err != nil {
+	if err := backend.CheckoutTag(ctx, project.Path, tag); err != nil {
 		return fmt.Errorf("git checkout failed: %v", err)
 	}
 
@@ -974,79 +745,138 @@ func (c *VersionController) checkoutTag(projectPath, tag string) error {
 }
 
 // checkoutBranch 检出指定分支
-func (c *VersionController) checkoutBranch(projectPath, branch string) error {
+func (c *VersionController) checkoutBranch(ctx context.Context, project models.Project, branch string) error {
+	backend := gitBackendFor(project)
+
 	// 先获取最新的远程分支信息
-	if _, err := c.executeGitCommand(projectPath, "fetch", "--all"); err != nil {
+	if err := backend.Fetch(ctx, project.Path); err != nil {
 		return fmt.Errorf("git fetch failed: %v", err)
 	}
 
-	// 处理远程分支名称
-	localBranch := branch
-	if strings.HasPrefix(branch, "origin/") {
-		localBranch = strings.TrimPrefix(branch, "origin/")
+	return backend.CheckoutBranch(ctx, project.Path, branch)
+}
+
+// loadProjectInfo 优先从缓存获取项目信息，缓存未命中时返回基本信息并异步更新
+func (c *VersionController) loadProjectInfo(ctx context.Context, project models.Project) ProjectInfo {
+	// 检查缓存
+	if cachedInfo, found := getProjectFromCache(project.Path); found {
+		if DebugMode {
+			fmt.Printf("📋 项目 %s 使用缓存数据\n", project.Name)
+		}
+		return applyProjectConfig(cachedInfo, project)
 	}
 
-	// 检查本地分支是否存在
-	if _, err := c.executeGitCommand(projectPath, "show-ref", "--verify", "--quiet", "refs/heads/"+localBranch); err != nil {
-		// 本地分支不存在，创建并跟踪远程分支
-		if _, err := c.executeGitCommand(projectPath, "checkout", "-b", localBranch, "origin/"+localBranch); err != nil {
-			return fmt.Errorf("创建并检出分支 %s 失败: %v", localBranch, err)
+	// 缓存未命中，使用快速模式获取基本信息
+	projectInfo := c.buildProjectInfo(ctx, project, true) // true = 快速模式
+
+	// 异步更新完整信息
+	c.updateProjectAsync(project)
+
+	if DebugMode {
+		fmt.Printf("📋 项目 %s 使用快速模式，已启动异步更新\n", project.Name)
+	}
+	return applyProjectConfig(projectInfo, project)
+}
+
+// applyProjectConfig 用当前配置更新项目信息中的分组和环境，缓存的项目信息可能早于配置修改
+func applyProjectConfig(info ProjectInfo, project models.Project) ProjectInfo {
+	info.Group = project.Group
+	info.Environment = project.Environment
+	info.Production = project.IsProduction()
+	info.DirtyPolicy = project.WorkingTreePolicy()
+	return info
+}
+
+// filterProjects 按分组和环境筛选项目，参数为空时不筛选
+func filterProjects(projects []models.Project, group, env string) []models.Project {
+	var filtered []models.Project
+	for _, project := range projects {
+		if group != "" && project.Group != group {
+			continue
 		}
-	} else {
-		// 本地分支存在，直接切换
-		if _, err := c.executeGitCommand(projectPath, "checkout", localBranch); err != nil {
-			return fmt.Errorf("切换到分支 %s 失败: %v", localBranch, err)
+		if env != "" && project.Environment != env {
+			continue
 		}
+		filtered = append(filtered, project)
+	}
+	return filtered
+}
 
-		// 更新本地分支到最新
-		if _, err := c.executeGitCommand(projectPath, "pull", "origin", localBranch); err != nil {
-			// pull 失败不是致命错误，只是记录警告
-			fmt.Printf("⚠️ 更新分支 %s 失败: %v\n", localBranch, err)
+// projectLabels 返回项目中出现的分组（按配置顺序）和环境（按从开发到生产的顺序）
+func projectLabels(projects []models.Project) ([]string, []string) {
+	var groups []string
+	seenGroups := map[string]bool{}
+	seenEnvs := map[string]bool{}
+	for _, project := range projects {
+		if project.Group != "" && !seenGroups[project.Group] {
+			seenGroups[project.Group] = true
+			groups = append(groups, project.Group)
 		}
+		seenEnvs[project.Environment] = true
 	}
 
-	return nil
+	var envs []string
+	for _, env := range models.Environments {
+		if seenEnvs[env] {
+			envs = append(envs, env)
+		}
+	}
+	return groups, envs
+}
+
+// groupProjects 按分组整理项目，分组按首次出现的顺序排列，未分组的项目排在最后
+func groupProjects(infos []ProjectInfo) []ProjectGroup {
+	var groups []ProjectGroup
+	index := map[string]int{}
+	var ungrouped []ProjectInfo
+	for _, info := range infos {
+		if info.Group == "" {
+			ungrouped = append(ungrouped, info)
+			continue
+		}
+		i, ok := index[info.Group]
+		if !ok {
+			i = len(groups)
+			index[info.Group] = i
+			groups = append(groups, ProjectGroup{Name: info.Group})
+		}
+		groups[i].Projects = append(groups[i].Projects, info)
+	}
+	if len(ungrouped) > 0 {
+		groups = append(groups, ProjectGroup{Projects: ungrouped})
+	}
+	return groups
 }
 
 // Index 显示项目列表和版本管理页面
 func (c *VersionController) Index() {
 	// 检查认证
-	RequireAuth(&c.Controller)
+	identity := RequireAuth(&c.Controller)
+	if identity == nil {
+		return
+	}
 
-	// 获取当前选中的项目
+	// 获取当前选中的项目和筛选条件
 	selectedProject := c.GetString("project", "")
+	filterGroup := c.GetString("group")
+	filterEnv := c.GetString("env")
 
-	// 获取所有启用的项目
-	enabledProjects := models.AppConfig.GetEnabledProjects()
+	// 获取当前用户可以访问的启用项目，再按分组和环境筛选
+	accessibleProjects := visibleProjects(identity)
+	groups, envs := projectLabels(accessibleProjects)
+	enabledProjects := filterProjects(accessibleProjects, filterGroup, filterEnv)
 
+	ctx := c.requestContext()
 	var projectInfos []ProjectInfo
 	var currentProjectInfo *ProjectInfo
 
 	for _, project := range enabledProjects {
 		// 添加更详细的诊断信息（仅在调试模式下）
 		if DebugMode {
-			c.debugProjectInfo(project)
+			c.debugProjectInfo(ctx, project)
 		}
 
-		var projectInfo ProjectInfo
-
-		// 检查缓存
-		if cachedInfo, found := getProjectFromCache(project.Path); found {
-			projectInfo = cachedInfo
-			if DebugMode {
-				fmt.Printf("📋 项目 %s 使用缓存数据\n", project.Name)
-			}
-		} else {
-			// 缓存未命中，使用快速模式获取基本信息
-			projectInfo = c.buildProjectInfo(project, true) // true = 快速模式
-
-			// 异步更新完整信息
-			c.updateProjectAsync(project)
-
-			if DebugMode {
-				fmt.Printf("📋 项目 %s 使用快速模式，已启动异步更新\n", project.Name)
-			}
-		}
+		projectInfo := c.loadProjectInfo(ctx, project)
 
 		// 设置当前项目标记
 		projectInfo.Current = project.Name == selectedProject
@@ -1058,16 +888,55 @@ func (c *VersionController) Index() {
 		}
 	}
 
+	// 选中的项目不在筛选结果中时默认选中第一个
+	if currentProjectInfo == nil && len(projectInfos) > 0 {
+		currentProjectInfo = &projectInfos[0]
+	}
+
+	// 查找当前项目上一次部署前的版本，用于一键回退
+	canDeploy := false
+	if currentProjectInfo != nil {
+		canDeploy = identity.CanDeploy(currentProjectInfo.Name)
+		if project := models.GetConfig().GetProjectByName(currentProjectInfo.Name); project != nil {
+			c.Data["Busy"] = projectBusy(*project)
+			// 缓存中的工作区状态可能已经过期，当前项目实时获取
+			if status, err := gitBackendFor(*project).Status(ctx, project.Path); err == nil {
+				currentProjectInfo.WorkingTree = workingTreeStatus(status)
+			}
+		}
+		if previous, err := models.LastSuccessfulDeploy(currentProjectInfo.Name); err == nil && previous != nil && previous.FromCommit != "" {
+			currentProjectInfo.PreviousRef = previous.FromRef
+		}
+	}
+
+	// 读取上一次操作通过 flash 传递的结果消息
+	flash := web.ReadFromRequest(&c.Controller)
+	c.Data["Success"] = flash.Data["success"]
+	c.Data["Warning"] = flash.Data["warning"]
+	c.Data["Error"] = flash.Data["error"]
+
 	c.Data["Projects"] = projectInfos
+	c.Data["ProjectGroups"] = groupProjects(projectInfos)
+	c.Data["Groups"] = groups
+	c.Data["Environments"] = envs
+	c.Data["FilterGroup"] = filterGroup
+	c.Data["FilterEnv"] = filterEnv
 	c.Data["CurrentProject"] = currentProjectInfo
-	c.Data["Title"] = models.AppConfig.UI.Title
+	c.Data["CanDeploy"] = canDeploy
+	c.Data["Username"] = identity.Name
+	c.Data["Role"] = identity.User.GlobalRole()
+	c.Data["IsAdmin"] = identity.IsAdmin()
+	c.Data["Title"] = models.GetConfig().UI.Title
 	c.TplName = "version/index.html"
 }
 
 // Checkout 执行版本回滚或分支切换
 func (c *VersionController) Checkout() {
 	// 检查认证
-	RequireAuth(&c.Controller)
+	identity := RequireAuth(&c.Controller)
+	if identity == nil {
+		return
+	}
 
 	tag := c.GetString("tag")
 	branch := c.GetString("branch")
@@ -1075,71 +944,268 @@ func (c *VersionController) Checkout() {
 
 	// 检查参数
 	if (tag == "" && branch == "") || projectName == "" {
-		c.Data["Error"] = "标签/分支和项目参数不能为空"
-		c.Redirect("/", 302)
+		c.checkoutError("标签/分支和项目参数不能为空", "/")
 		return
 	}
 
 	if tag != "" && branch != "" {
-		c.Data["Error"] = "不能同时指定标签和分支"
-		c.Redirect("/", 302)
+		c.checkoutError("不能同时指定标签和分支", "/")
 		return
 	}
 
 	// 获取项目信息
-	project := models.AppConfig.GetProjectByName(projectName)
+	project := models.GetConfig().GetProjectByName(projectName)
 	if project == nil {
-		c.Data["Error"] = fmt.Sprintf("项目 %s 不存在或未启用", projectName)
-		c.Redirect("/", 302)
+		c.checkoutError(fmt.Sprintf("项目 %s 不存在或未启用", projectName), "/")
 		return
 	}
 
-	var err error
-	var successMsg string
+	if !c.checkDeployPermission(identity, project.Name) {
+		return
+	}
+	if !productionConfirmed(*project, c.GetString("confirm")) {
+		c.checkoutError(productionConfirmMessage(*project), "/?project="+projectName)
+		return
+	}
 
-	if tag != "" {
-		// 标签切换
-		err = c.checkoutTag(project.Path, tag)
-		if err != nil {
-			c.Data["Error"] = fmt.Sprintf("项目 %s 切换到标签 %s 失败: %v", projectName, tag, err)
-		} else {
-			successMsg = fmt.Sprintf("项目 %s 成功切换到标签 %s", projectName, tag)
-		}
-	} else if branch != "" {
-		// 分支切换
-		err = c.checkoutBranch(project.Path, branch)
-		if err != nil {
-			c.Data["Error"] = fmt.Sprintf("项目 %s 切换到分支 %s 失败: %v", projectName, branch, err)
-		} else {
-			successMsg = fmt.Sprintf("项目 %s 成功切换到分支 %s", projectName, branch)
+	targetType, targetRef := "tag", tag
+	if branch != "" {
+		targetType, targetRef = "branch", branch
+	}
+	if err := checkRefName(targetRef); err != nil {
+		c.checkoutError(fmt.Sprintf("%s名 %s 无效: %v", deployTargetLabel(targetType), targetRef, err), "/?project="+projectName)
+		return
+	}
+
+	unlock, err := lockProject(c.requestContext(), *project, deployOperation(targetType, targetRef), identity.DisplayName())
+	if err != nil {
+		c.lockError(*project, err)
+		return
+	}
+	defer unlock()
+
+	if ok, message := discardConfirmed(c.requestContext(), *project, c.GetString("discard_changes")); !ok {
+		c.checkoutError(message, "/?project="+projectName)
+		return
+	}
+
+	result := c.deployProject(c.deployContext(), *project, targetType, targetRef)
+	recordDeployHistory(identity.DisplayName(), "checkout", result)
+	c.finishDeploy(*project, result)
+}
+
+// Revert 将项目恢复到上一次部署之前的版本
+func (c *VersionController) Revert() {
+	// 检查认证
+	identity := RequireAuth(&c.Controller)
+	if identity == nil {
+		return
+	}
+
+	projectName := c.GetString("project")
+	if projectName == "" {
+		c.checkoutError("项目参数不能为空", "/")
+		return
+	}
+
+	project := models.GetConfig().GetProjectByName(projectName)
+	if project == nil {
+		c.checkoutError(fmt.Sprintf("项目 %s 不存在或未启用", projectName), "/")
+		return
+	}
+
+	if !c.checkDeployPermission(identity, project.Name) {
+		return
+	}
+	if !productionConfirmed(*project, c.GetString("confirm")) {
+		c.checkoutError(productionConfirmMessage(*project), "/?project="+projectName)
+		return
+	}
+
+	// 加锁后再读取部署历史，确保回退到最近一次部署之前的版本
+	unlock, err := lockProject(c.requestContext(), *project, "回退", identity.DisplayName())
+	if err != nil {
+		c.lockError(*project, err)
+		return
+	}
+	defer unlock()
+
+	if ok, message := discardConfirmed(c.requestContext(), *project, c.GetString("discard_changes")); !ok {
+		c.checkoutError(message, "/?project="+projectName)
+		return
+	}
+
+	previous, err := models.LastSuccessfulDeploy(project.Name)
+	if err != nil {
+		c.checkoutError(fmt.Sprintf("读取项目 %s 的部署历史失败: %v", projectName, err), "/?project="+projectName)
+		return
+	}
+	if previous == nil || previous.FromCommit == "" {
+		c.checkoutError(fmt.Sprintf("项目 %s 没有可回退的部署记录", projectName), "/?project="+projectName)
+		return
+	}
+
+	result := c.revertProject(c.deployContext(), *project, previous)
+	recordDeployHistory(identity.DisplayName(), "revert", result)
+	c.finishDeploy(*project, result)
+}
+
+// productionConfirmed 生产环境项目需要在 confirm 参数中输入项目名称确认检出和回退
+func productionConfirmed(project models.Project, confirm string) bool {
+	return !project.IsProduction() || confirm == project.Name
+}
+
+// productionConfirmMessage 生产环境项目缺少确认时的提示
+func productionConfirmMessage(project models.Project) string {
+	return fmt.Sprintf("项目 %s 是生产环境，请输入项目名称确认操作", project.Name)
+}
+
+// discardConfirmed 使用 reset 策略的项目在工作区有未提交修改时，需要在 discard_changes 参数中确认丢弃这些修改
+// 返回是否可以继续检出，以及需要确认时的提示
+func discardConfirmed(ctx context.Context, project models.Project, discard string) (bool, string) {
+	if project.WorkingTreePolicy() != models.DirtyPolicyReset || discard == "true" || discard == "1" || discard == "on" {
+		return true, ""
+	}
+	// 获取状态失败时交给检出流程报告错误
+	status, err := gitBackendFor(project).Status(ctx, project.Path)
+	if err != nil || len(status.Modified) == 0 {
+		return true, ""
+	}
+	return false, fmt.Sprintf("项目 %s 的工作区有 %d 个文件未提交的修改（%s），检出将丢弃这些修改，请确认后重试",
+		project.Name, len(status.Modified), summarizeFiles(status.Modified))
+}
+
+// checkDeployPermission 检查用户是否可以对项目执行变更操作，无权限时返回 403 或提示消息
+func (c *VersionController) checkDeployPermission(identity *Identity, projectName string) bool {
+	if identity.CanDeploy(projectName) {
+		return true
+	}
+
+	message := fmt.Sprintf("无权对项目 %s 执行变更操作", projectName)
+	redirect := "/"
+	if identity.CanAccessProject(projectName) {
+		redirect = "/?project=" + projectName
+	} else {
+		message = fmt.Sprintf("无权访问项目 %s", projectName)
+	}
+	c.Ctx.Output.SetStatus(http.StatusForbidden)
+	c.checkoutError(message, redirect)
+	return false
+}
+
+// lockError 返回获取项目锁失败的提示，项目正忙时返回 409
+func (c *VersionController) lockError(project models.Project, err error) {
+	var busy *ProjectBusyError
+	if errors.As(err, &busy) {
+		c.Ctx.Output.SetStatus(http.StatusConflict)
+	}
+	c.checkoutError(err.Error(), "/?project="+project.Name)
+}
+
+// finishDeploy 刷新缓存并返回部署结果（JSON 或 flash 消息 + 重定向）
+func (c *VersionController) finishDeploy(project models.Project, result *DeployResult) {
+	c.refreshAfterDeploy(project, result)
+
+	if c.wantsJSON() {
+		c.Data["json"] = map[string]interface{}{
+			"success":     result.Success,
+			"rolled_back": result.RolledBack,
+			"message":     result.Message,
+			"data":        result,
 		}
+		c.ServeJSON()
+		return
 	}
 
-	if successMsg != "" {
-		c.Data["Success"] = successMsg
+	flash := web.NewFlash()
+	switch {
+	case result.Success:
+		flash.Success("%s", flashMessage(result.Message))
+	case result.RolledBack:
+		flash.Warning("%s", flashMessage(result.Message))
+	default:
+		flash.Error("%s", flashMessage(result.Message))
+	}
+	flash.Store(&c.Controller)
 
-		// 切换成功后立即清除缓存并更新项目信息
-		cacheMutex.Lock()
-		delete(projectCache, project.Path)
-		cacheMutex.Unlock()
+	// 重定向回主页面，保持当前项目选中状态
+	c.Redirect("/?project="+project.Name, 302)
+}
 
+// refreshAfterDeploy 工作区发生变化后（包括回滚的情况）清除缓存并更新项目信息
+func (c *VersionController) refreshAfterDeploy(project models.Project, result *DeployResult) {
+	if result.ToCommit != "" {
 		// 立即获取最新的项目状态并缓存
-		updatedInfo := c.buildProjectInfo(*project, false) // false = 完整模式
-		setProjectCache(project.Path, updatedInfo)
+		c.refreshProjectInfo(c.deployContext(), project)
 
 		if DebugMode {
-			fmt.Printf("✅ 切换后已更新项目 %s 的缓存信息\n", projectName)
+			fmt.Printf("✅ 切换后已更新项目 %s 的缓存信息\n", project.Name)
 		}
 	}
+}
 
-	// 重定向回主页面，保持当前项目选中状态
-	c.Redirect("/?project="+projectName, 302)
+// wantsJSON 判断客户端是否期望 JSON 响应（AJAX 请求或 Accept: application/json）
+func (c *VersionController) wantsJSON() bool {
+	return c.Ctx.Input.IsAjax() || c.Ctx.Input.AcceptsJSON()
+}
+
+// checkoutError 返回检出请求的参数错误
+func (c *VersionController) checkoutError(message, redirect string) {
+	if c.wantsJSON() {
+		c.Data["json"] = map[string]interface{}{
+			"success": false,
+			"message": message,
+		}
+		c.ServeJSON()
+		return
+	}
+
+	flash := web.NewFlash()
+	flash.Error("%s", flashMessage(message))
+	flash.Store(&c.Controller)
+	c.Redirect(redirect, 302)
+}
+
+// flashMessage 截断过长的消息，避免超出 Cookie 大小限制
+func flashMessage(message string) string {
+	const maxLen = 1000
+	if runes := []rune(message); len(runes) > maxLen {
+		return string(runes[:maxLen]) + "..."
+	}
+	return message
+}
+
+// refreshProjectInfo 清除项目缓存并重新获取完整的项目信息，调用方需要持有项目锁
+func (c *VersionController) refreshProjectInfo(ctx context.Context, project models.Project) ProjectInfo {
+	// 清除缓存
+	cacheMutex.Lock()
+	delete(projectCache, project.Path)
+	cacheMutex.Unlock()
+
+	// 重新获取项目信息
+	projectInfo := c.buildProjectInfo(ctx, project, false) // false = 完整模式
+	setProjectCache(project.Path, projectInfo)
+	return applyProjectConfig(projectInfo, project)
+}
+
+// refreshProjectLocked 获取项目锁后刷新项目信息，项目正忙时返回 ProjectBusyError
+func (c *VersionController) refreshProjectLocked(ctx context.Context, project models.Project, user string) (ProjectInfo, error) {
+	unlock, err := lockProject(ctx, project, "刷新", user)
+	if err != nil {
+		return ProjectInfo{}, err
+	}
+	defer unlock()
+
+	return c.refreshProjectInfo(ctx, project), nil
 }
 
 // RefreshProject 刷新项目缓存
 func (c *VersionController) RefreshProject() {
-	// 检查认证
-	RequireAuth(&c.Controller)
+	// 检查认证（支持 API 令牌）
+	identity := requireJSONAuth(&c.Controller)
+	if identity == nil {
+		return
+	}
 
 	projectName := c.GetString("project")
 	if projectName == "" {
@@ -1152,7 +1218,7 @@ func (c *VersionController) RefreshProject() {
 	}
 
 	// 获取项目信息
-	project := models.AppConfig.GetProjectByName(projectName)
+	project := models.GetConfig().GetProjectByName(projectName)
 	if project == nil {
 		c.Data["json"] = map[string]interface{}{
 			"success": false,
@@ -1162,14 +1228,34 @@ func (c *VersionController) RefreshProject() {
 		return
 	}
 
-	// 清除缓存
-	cacheMutex.Lock()
-	delete(projectCache, project.Path)
-	cacheMutex.Unlock()
+	// 刷新会执行 fetch 并占用项目锁，需要变更权限
+	if !identity.CanDeploy(project.Name) {
+		message := fmt.Sprintf("无权刷新项目 %s", projectName)
+		if !identity.CanAccessProject(project.Name) {
+			message = fmt.Sprintf("无权访问项目 %s", projectName)
+		}
+		c.Ctx.Output.SetStatus(http.StatusForbidden)
+		c.Data["json"] = map[string]interface{}{
+			"success": false,
+			"message": message,
+		}
+		c.ServeJSON()
+		return
+	}
 
-	// 重新获取项目信息
-	projectInfo := c.buildProjectInfo(*project, false) // false = 完整模式
-	setProjectCache(project.Path, projectInfo)
+	projectInfo, err := c.refreshProjectLocked(c.requestContext(), *project, identity.DisplayName())
+	if err != nil {
+		var busy *ProjectBusyError
+		if errors.As(err, &busy) {
+			c.Ctx.Output.SetStatus(http.StatusConflict)
+		}
+		c.Data["json"] = map[string]interface{}{
+			"success": false,
+			"message": err.Error(),
+		}
+		c.ServeJSON()
+		return
+	}
 
 	c.Data["json"] = map[string]interface{}{
 		"success": true,
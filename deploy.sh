@@ -121,9 +121,9 @@ EOF
 
 sudo chmod +x gover
 
-# 设置配置文件权限（如果不存在则使用默认配置）
+# 设置配置文件权限（配置文件不存在或无效时服务无法启动）
 if [ ! -f config.yaml ]; then
-    echo "📝 使用默认配置文件"
+    echo "⚠️ 未找到 config.yaml，请在启动前创建配置文件并运行 ./gover check-config 检查"
 fi
 
 sudo chmod 600 config.yaml 2>/dev/null || true
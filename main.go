@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 
 	"gover/controllers"
 	"gover/models"
@@ -24,48 +23,32 @@ func init() {
 	// Beego 配置将通过 app.conf 文件自动加载
 }
 
-// clearSessionFiles 清除 Session 相关文件
-func clearSessionFiles() {
-	// 清除可能的 Session 临时文件
-	tempDirs := []string{
-		"/tmp",
-		os.TempDir(),
-		"./tmp",
-		"./sessions",
-	}
-
-	for _, dir := range tempDirs {
-		if _, err := os.Stat(dir); err == nil {
-			// 查找并删除 Session 文件
-			pattern := filepath.Join(dir, "session_*")
-			files, _ := filepath.Glob(pattern)
-			for _, file := range files {
-				if err := os.Remove(file); err != nil {
-					fmt.Printf("警告: 删除文件失败 %s: %v\n", file, err)
-				}
-			}
-
-			// 查找并删除 gorilla session 文件
-			pattern = filepath.Join(dir, "gorilla_*")
-			files, _ = filepath.Glob(pattern)
-			for _, file := range files {
-				if err := os.Remove(file); err != nil {
-					fmt.Printf("警告: 删除文件失败 %s: %v\n", file, err)
-				}
-			}
-		}
+// configPathFromEnv 返回 GOVER_CONFIG 环境变量指定的配置文件路径，未设置时使用默认路径
+func configPathFromEnv() string {
+	if path := os.Getenv("GOVER_CONFIG"); path != "" {
+		return path
 	}
+	return models.DefaultConfigPath
 }
 
 func main() {
 	// 解析命令行参数
-	clearSessions := flag.Bool("clear-sessions", false, "清除所有 Session 文件并退出")
+	configFile := flag.String("config", configPathFromEnv(), "配置文件路径（也可通过 GOVER_CONFIG 环境变量设置）")
+	clearSessions := flag.Bool("clear-sessions", false, "吊销所有 Session 并退出（等同于 gover sessions revoke-all）")
 	showVersion := flag.Bool("version", false, "显示版本信息")
 	debugMode := flag.Bool("debug", false, "启用调试模式，显示详细的项目诊断信息")
 	fixGitPermissions := flag.Bool("fix-git", false, "修复所有项目的 Git 权限问题并退出")
-	fastMode := flag.Bool("fast", false, "启用快速模式，减少 Git 操作以提高响应速度")
+	fastMode := flag.Bool("fast", false, "已废弃：标签和分支信息已批量获取，保留该参数仅为兼容旧的启动脚本")
 	skipFetch := flag.Bool("skip-fetch", false, "跳过 Git fetch 操作，使用本地数据")
+	gitTimeout := flag.Duration("git-timeout", controllers.GitCommandTimeout, "本地 Git 命令的超时时间")
+	gitNetworkTimeout := flag.Duration("git-network-timeout", controllers.GitNetworkTimeout, "fetch、pull 等访问远程仓库的 Git 命令的超时时间")
 	flag.Parse()
+	models.SetConfigPath(*configFile)
+
+	// 子命令（如 gover token create、gover -config /etc/gover.yaml check-config）
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args()))
+	}
 
 	// 如果指定了显示版本参数
 	if *showVersion {
@@ -75,14 +58,9 @@ func main() {
 		os.Exit(0)
 	}
 
-	// 如果指定了清除 Session 参数
+	// 如果指定了清除 Session 参数（兼容旧版本，等同于 gover sessions revoke-all）
 	if *clearSessions {
-		fmt.Printf("🧹 正在清除 Session 数据...\n")
-		clearSessionFiles()
-		controllers.ResetSessionStore()
-		fmt.Printf("✅ 所有 Session 数据已清除\n")
-		fmt.Printf("💡 所有用户需要重新登录\n")
-		os.Exit(0)
+		os.Exit(runCommand([]string{"sessions", "revoke-all"}))
 	}
 
 	// 如果指定了修复 Git 权限参数
@@ -90,10 +68,13 @@ func main() {
 		fmt.Printf("🔧 正在修复 Git 权限问题...\n")
 
 		// 先加载配置
-		models.InitConfig()
+		if err := models.InitConfig(); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
 
 		successCount := 0
-		for _, project := range models.AppConfig.GetEnabledProjects() {
+		for _, project := range models.GetConfig().GetEnabledProjects() {
 			fmt.Printf("📁 处理项目: %s (%s)\n", project.Name, project.Path)
 
 			// 检查项目路径是否存在
@@ -123,10 +104,20 @@ func main() {
 
 	// 立即输出程序信息，覆盖 Beego 的配置警告
 	fmt.Printf("\n🚀 Gover %s - Git 版本管理工具启动中...\n", Version)
-	fmt.Printf("📝 使用 YAML 配置文件 (config.yaml)\n")
+	fmt.Printf("📝 使用 YAML 配置文件 (%s)\n", models.ConfigPath())
+
+	// 初始化配置（会自动创建 app.conf 文件），配置无效时直接退出
+	if err := models.InitConfig(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		fmt.Printf("💡 运行 gover check-config 查看配置问题\n")
+		os.Exit(1)
+	}
 
-	// 初始化配置（会自动创建 app.conf 文件）
-	models.InitConfig()
+	// 会话签名密钥：未配置或使用默认值时自动生成
+	if err := checkSessionKeys(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
 
 	// 设置嵌入的模板文件
 	if err := setupEmbeddedTemplates(); err != nil {
@@ -141,45 +132,73 @@ func main() {
 	}
 
 	// 设置性能模式
-	controllers.FastMode = *fastMode
 	controllers.SkipFetch = *skipFetch
 	if *fastMode {
-		fmt.Printf("⚡ 快速模式已启用\n")
+		fmt.Printf("⚠️ --fast 已废弃，标签和分支信息已批量获取，无需快速模式\n")
 	}
 	if *skipFetch {
 		fmt.Printf("📡 跳过 fetch 操作\n")
 	}
+	controllers.GitCommandTimeout = *gitTimeout
+	controllers.GitNetworkTimeout = *gitNetworkTimeout
+
+	// CSRF 防护：所有 POST 请求都需要携带会话的 CSRF 令牌（Bearer 令牌认证的 API 除外）
+	web.InsertFilter("/*", web.BeforeRouter, controllers.CSRFFilter)
+
+	// 模板函数：环境的中文名称
+	web.AddFuncMap("envLabel", models.EnvironmentLabel)
 
 	// 设置路由
 	web.Router("/", &controllers.VersionController{}, "get,post:Index")
 	web.Router("/checkout", &controllers.VersionController{}, "post:Checkout")
+	web.Router("/revert", &controllers.VersionController{}, "post:Revert")
 	web.Router("/refresh", &controllers.VersionController{}, "post:RefreshProject")
+	web.Router("/history", &controllers.HistoryController{}, "get:Index")
+	web.Router("/history/list", &controllers.HistoryController{}, "get:List")
+	web.Router("/api/v1/projects", &controllers.APIController{}, "get:ListProjects")
+	web.Router("/api/v1/projects/:name", &controllers.APIController{}, "get:GetProject")
+	web.Router("/api/v1/projects/:name/checkout", &controllers.APIController{}, "post:Checkout")
+	web.Router("/api/v1/projects/:name/revert", &controllers.APIController{}, "post:Revert")
+	web.Router("/api/v1/projects/:name/refresh", &controllers.APIController{}, "post:Refresh")
+	web.Router("/api/v1/projects/:name/history", &controllers.APIController{}, "get:ProjectHistory")
+	web.Router("/api/v1/history", &controllers.APIController{}, "get:History")
+	web.Router("/admin/lockouts", &controllers.AdminController{}, "get:Lockouts")
+	web.Router("/admin/lockouts/clear", &controllers.AdminController{}, "post:ClearLockout")
+	web.Router("/admin/projects", &controllers.AdminController{}, "get:Projects")
+	web.Router("/admin/projects/edit", &controllers.AdminController{}, "get:EditProject")
+	web.Router("/admin/projects/save", &controllers.AdminController{}, "post:SaveProject")
+	web.Router("/admin/projects/toggle", &controllers.AdminController{}, "post:ToggleProject")
+	web.Router("/admin/projects/delete", &controllers.AdminController{}, "post:DeleteProject")
+	web.Router("/admin/projects/clone", &controllers.AdminController{}, "get:CloneProject;post:StartClone")
+	web.Router("/admin/projects/clone/status", &controllers.AdminController{}, "get:CloneStatus")
+	web.Router("/admin/projects/clone/job", &controllers.AdminController{}, "get:CloneJobStatus")
+	web.Router("/admin/sessions", &controllers.AdminController{}, "get:Sessions")
+	web.Router("/admin/sessions/revoke", &controllers.AdminController{}, "post:RevokeSession")
+	web.Router("/admin/sessions/revoke-all", &controllers.AdminController{}, "post:RevokeAllSessions")
 	web.Router("/login", &controllers.AuthController{}, "get,post:Login")
 	web.Router("/logout", &controllers.AuthController{}, "get:Logout")
 
-	// 从 YAML 配置覆盖端口和主机设置
-	web.BConfig.Listen.HTTPPort = models.AppConfig.Server.Port
-	web.BConfig.Listen.HTTPAddr = models.AppConfig.Server.Host
+	// 从 YAML 配置覆盖端口、主机和 TLS 设置
+	if err := setupListeners(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
 
 	// 启动服务
 	fmt.Printf("✅ 配置加载完成\n")
-	fmt.Printf("📡 服务地址: http://%s:%d\n", models.AppConfig.Server.Host, models.AppConfig.Server.Port)
-	fmt.Printf("👤 用户名: %s\n", models.AppConfig.Auth.Username)
-	fmt.Printf("🔐 密码: %s\n", models.AppConfig.Auth.Password)
-	fmt.Printf("📁 管理 %d 个项目\n", len(models.AppConfig.GetEnabledProjects()))
-
-	// 检查项目权限并提供修复建议
-	for _, project := range models.AppConfig.GetEnabledProjects() {
-		if _, err := os.Stat(project.Path); err != nil {
-			fmt.Printf("⚠️ 项目路径不存在: %s\n", project.Path)
-		}
-	}
+	fmt.Printf("📡 服务地址: %s\n", serverURL())
+	fmt.Printf("👤 已配置 %d 个用户\n", len(models.GetConfig().AllUsers()))
+	fmt.Printf("📁 管理 %d 个项目\n", len(models.GetConfig().GetEnabledProjects()))
 
+	// 路径不存在的项目已在加载配置时提示，这里只提供权限修复建议
 	fmt.Printf("\n💡 提示: 如果遇到 Git 权限问题，可以运行以下命令修复:\n")
-	for _, project := range models.AppConfig.GetEnabledProjects() {
+	for _, project := range models.GetConfig().GetEnabledProjects() {
 		fmt.Printf("   git config --global --add safe.directory %s\n", project.Path)
 	}
 
+	// 监听配置文件变化，修改项目、用户等配置无需重启
+	models.WatchConfig(models.DefaultConfigWatchInterval, onConfigReload)
+
 	fmt.Printf("\n🌟 服务启动中...\n\n")
 
 	web.Run()
@@ -1,33 +1,170 @@
 package models
 
 import (
+	"crypto/tls"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
-
-	"gopkg.in/yaml.v3"
+	"sync"
 )
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port int    `yaml:"port"`
-	Host string `yaml:"host"`
+	Port int       `yaml:"port"` // 服务端口，启用 TLS 时为 HTTPS 端口
+	Host string    `yaml:"host"`
+	TLS  TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig HTTPS 配置
+type TLSConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	CertFile     string `yaml:"cert_file"`     // 证书文件（PEM）
+	KeyFile      string `yaml:"key_file"`      // 私钥文件（PEM）
+	MinVersion   string `yaml:"min_version"`   // 最低 TLS 版本: 1.2（默认）或 1.3
+	RedirectPort int    `yaml:"redirect_port"` // 大于 0 时在该端口监听 HTTP 并重定向到 HTTPS
 }
 
-// AuthConfig 认证配置
+// MinTLSVersion 返回最低 TLS 版本对应的常量
+func (t *TLSConfig) MinTLSVersion() (uint16, error) {
+	switch t.MinVersion {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("不支持的 TLS 最低版本: %s（可选 1.2、1.3）", t.MinVersion)
+	}
+}
+
+// AuthConfig 认证配置（旧版单用户配置，配置了 users 时忽略）
 type AuthConfig struct {
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"` // 从文件读取密码哈希，与 password 二选一
+}
+
+// HookStep 检出前后执行的 Shell 步骤
+type HookStep struct {
+	Name    string            `yaml:"name"`
+	Command string            `yaml:"command"`
+	Dir     string            `yaml:"dir"`     // 工作目录，相对路径基于项目路径，为空时使用项目路径
+	Timeout int               `yaml:"timeout"` // 超时时间(秒)，为空时使用默认值
+	Env     map[string]string `yaml:"env"`     // 额外的环境变量
+}
+
+// HealthCheck 检出后的 HTTP 健康检查配置
+type HealthCheck struct {
+	URL            string `yaml:"url"`
+	ExpectedStatus int    `yaml:"expected_status"` // 期望的状态码，默认 200
+	BodyContains   string `yaml:"body_contains"`   // 响应体需要包含的内容，为空时不检查
+	Retries        int    `yaml:"retries"`         // 最大尝试次数，默认 5
+	Interval       int    `yaml:"interval"`        // 两次尝试之间的间隔(秒)，默认 3
+	Timeout        int    `yaml:"timeout"`         // 单次请求超时时间(秒)，默认 5
+}
+
+// 项目环境
+const (
+	EnvDevelopment = "development"
+	EnvTesting     = "testing"
+	EnvStaging     = "staging"
+	EnvProduction  = "production" // 生产环境，检出和回退需要输入项目名称二次确认
+)
+
+// Environments 支持的项目环境，按从开发到生产的顺序排列
+var Environments = []string{EnvDevelopment, EnvTesting, EnvStaging, EnvProduction}
+
+// ValidEnvironment 检查环境名称是否有效，空字符串表示未设置
+func ValidEnvironment(env string) bool {
+	if env == "" {
+		return true
+	}
+	for _, known := range Environments {
+		if env == known {
+			return true
+		}
+	}
+	return false
+}
+
+// EnvironmentLabel 返回环境的中文名称，用于页面显示
+func EnvironmentLabel(env string) string {
+	switch env {
+	case EnvDevelopment:
+		return "开发"
+	case EnvTesting:
+		return "测试"
+	case EnvStaging:
+		return "预发布"
+	case EnvProduction:
+		return "生产"
+	default:
+		return env
+	}
+}
+
+// Git 后端
+const (
+	GitBackendExec  = "exec"   // 调用系统的 git 命令（默认）
+	GitBackendGoGit = "go-git" // 进程内的纯 Go 实现，不依赖 git 命令
+)
+
+// ValidGitBackend 检查 Git 后端名称是否有效，空字符串表示默认后端
+func ValidGitBackend(backend string) bool {
+	return backend == "" || backend == GitBackendExec || backend == GitBackendGoGit
+}
+
+// 工作区有未提交修改时的检出策略
+const (
+	DirtyPolicyRefuse = "refuse" // 拒绝检出（默认）
+	DirtyPolicyStash  = "stash"  // 检出前 stash，检出后恢复
+	DirtyPolicyReset  = "reset"  // 确认后丢弃修改（git reset --hard）
+)
+
+// ValidDirtyPolicy 检查检出策略是否有效，空字符串表示默认策略
+func ValidDirtyPolicy(policy string) bool {
+	return policy == "" || policy == DirtyPolicyRefuse || policy == DirtyPolicyStash || policy == DirtyPolicyReset
 }
 
 // Project 项目配置
 type Project struct {
-	Name        string `yaml:"name"`
-	Path        string `yaml:"path"`
-	Description string `yaml:"description"`
-	Enabled     bool   `yaml:"enabled"`
+	Name         string       `yaml:"name"`
+	Path         string       `yaml:"path"`
+	Description  string       `yaml:"description"`
+	Enabled      bool         `yaml:"enabled"`
+	Group        string       `yaml:"group"`         // 分组，页面按分组显示，为空时归入“未分组”
+	Environment  string       `yaml:"environment"`   // development / testing / staging / production
+	GitBackend   string       `yaml:"git_backend"`   // exec（默认）或 go-git
+	DirtyPolicy  string       `yaml:"dirty_policy"`  // 工作区有未提交修改时的检出策略：refuse（默认）、stash 或 reset
+	PreCheckout  []HookStep   `yaml:"pre_checkout"`  // 检出前执行，任一步骤失败将中止检出
+	PostCheckout []HookStep   `yaml:"post_checkout"` // 检出后执行，例如构建、重启服务
+	HealthCheck  *HealthCheck `yaml:"health_check"`  // 检出后的健康检查，失败时自动回滚
+}
+
+// IsProduction 是否为生产环境项目
+func (p *Project) IsProduction() bool {
+	return p.Environment == EnvProduction
+}
+
+// WorkingTreePolicy 返回工作区有未提交修改时的检出策略，未配置时为 refuse
+func (p *Project) WorkingTreePolicy() string {
+	if p.DirtyPolicy == "" {
+		return DirtyPolicyRefuse
+	}
+	return p.DirtyPolicy
+}
+
+// PathError 检查项目路径是否为存在的目录，路径不可用（如尚未挂载）时返回错误
+func (p *Project) PathError() error {
+	stat, err := os.Stat(p.Path)
+	if err != nil {
+		return fmt.Errorf("路径不存在: %s", p.Path)
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("不是目录: %s", p.Path)
+	}
+	return nil
 }
 
 // UIConfig 界面配置
@@ -39,10 +176,20 @@ type UIConfig struct {
 
 // SecurityConfig 安全配置
 type SecurityConfig struct {
-	EnableAuth     bool   `yaml:"enable_auth"`
-	SessionTimeout int    `yaml:"session_timeout"`
-	SessionSecret  string `yaml:"session_secret"`
-	RememberMeDays int    `yaml:"remember_me_days"`
+	EnableAuth             bool     `yaml:"enable_auth"`
+	SessionTimeout         int      `yaml:"session_timeout"`
+	SessionSecret          string   `yaml:"session_secret"`           // 为空时自动生成随机密钥保存到 <data_dir>/session_keys.json
+	SessionSecretFile      string   `yaml:"session_secret_file"`      // 从文件读取 session_secret，与 session_secret 二选一
+	PreviousSessionSecrets []string `yaml:"previous_session_secrets"` // 轮换前的旧密钥，只用于验证已签发的会话
+	RememberMeDays         int      `yaml:"remember_me_days"`
+	TokenFile              string   `yaml:"token_file"` // API 令牌文件，默认 <data_dir>/tokens.yaml
+
+	// 登录防暴力破解
+	LoginMaxAttempts      int  `yaml:"login_max_attempts"`        // 同一用户名连续失败多少次后锁定，默认 5
+	LoginMaxAttemptsPerIP int  `yaml:"login_max_attempts_per_ip"` // 同一 IP 连续失败多少次后锁定，默认 20
+	LoginBackoff          int  `yaml:"login_backoff"`             // 失败后的初始等待时间(秒)，每次失败翻倍，默认 1
+	LoginLockout          int  `yaml:"login_lockout"`             // 锁定时长(秒)，默认 900
+	TrustProxy            bool `yaml:"trust_proxy"`               // 是否信任 X-Forwarded-For / X-Real-IP 获取客户端 IP（反向代理部署时开启）
 }
 
 // LoggingConfig 日志配置
@@ -53,18 +200,69 @@ type LoggingConfig struct {
 
 // Config 完整配置结构
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Auth     AuthConfig     `yaml:"auth"`
-	Projects []Project      `yaml:"projects"`
-	UI       UIConfig       `yaml:"ui"`
-	Security SecurityConfig `yaml:"security"`
-	Logging  LoggingConfig  `yaml:"logging"`
+	Server    ServerConfig   `yaml:"server"`
+	Auth      AuthConfig     `yaml:"auth"`
+	Users     []UserConfig   `yaml:"users"` // 多用户配置，每个用户可以有不同的角色和项目授权
+	Projects  []Project      `yaml:"projects"`
+	UI        UIConfig       `yaml:"ui"`
+	Security  SecurityConfig `yaml:"security"`
+	Logging   LoggingConfig  `yaml:"logging"`
+	DataDir   string         `yaml:"data_dir"`   // 运行数据目录（部署历史等），默认 data
+	APITokens []APIToken     `yaml:"api_tokens"` // 机器客户端使用的 API 令牌（只保存哈希）
 }
 
-var AppConfig *Config
+// DefaultConfigPath 默认配置文件路径，可通过 GOVER_CONFIG 环境变量或 -config 参数修改
+const DefaultConfigPath = "config.yaml"
+
+var (
+	appConfig  *Config
+	configMu   sync.RWMutex
+	configPath = DefaultConfigPath
+)
+
+// GetConfig 返回当前生效的配置；热重载时整体替换，调用方不要修改返回的配置
+func GetConfig() *Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return appConfig
+}
+
+// setConfig 替换当前生效的配置
+func setConfig(config *Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	appConfig = config
+}
+
+// ConfigPath 返回服务使用的配置文件路径
+func ConfigPath() string {
+	return configPath
+}
+
+// SetConfigPath 设置配置文件路径，需要在加载配置之前调用
+func SetConfigPath(path string) {
+	if path != "" {
+		configPath = path
+	}
+}
 
-// LoadConfig 加载配置文件
+// LoadConfig 加载并校验配置文件，通过后设为当前配置
 func LoadConfig(configPath string) (*Config, error) {
+	config, err := ParseConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range config.Warnings() {
+		fmt.Printf("⚠️ %s（项目暂不可用）\n", warning.Error())
+	}
+	setConfig(config)
+	return config, nil
+}
+
+// ParseConfigFile 严格解析并校验配置文件，不影响当前配置
+// 值中的 ${VAR} 会替换为环境变量，*_file 字段指向的密钥文件会被读取
+// 未知字段、类型错误和校验问题会一次性以 ValidationErrors 返回
+func ParseConfigFile(configPath string) (*Config, error) {
 	// 安全检查：验证配置文件路径
 	cleanPath := filepath.Clean(configPath)
 	if strings.Contains(cleanPath, "..") {
@@ -75,17 +273,39 @@ func LoadConfig(configPath string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	return parseConfigData(data)
+}
 
+// parseConfigData 解析并校验配置内容
+func parseConfigData(data []byte) (*Config, error) {
 	var config Config
-	err = yaml.Unmarshal(data, &config)
+	problems, err := decodeConfig(data, &config)
 	if err != nil {
+		// YAML 语法错误，无法继续校验
 		return nil, err
 	}
-
-	AppConfig = &config
+	problems = append(problems, config.resolveSecretFiles()...)
+	problems = append(problems, config.validate()...)
+	if len(problems) > 0 {
+		return nil, problems
+	}
 	return &config, nil
 }
 
+// ReloadConfig 重新读取配置文件，校验通过后替换当前配置，失败时保留原配置
+func ReloadConfig() (*Config, error) {
+	return LoadConfig(configPath)
+}
+
+// DataPath 返回数据目录下指定文件的路径
+func (c *Config) DataPath(name string) string {
+	dir := c.DataDir
+	if dir == "" {
+		dir = "data"
+	}
+	return filepath.Join(dir, name)
+}
+
 // GetEnabledProjects 获取启用的项目列表
 func (c *Config) GetEnabledProjects() []Project {
 	var enabledProjects []Project
@@ -107,54 +327,18 @@ func (c *Config) GetProjectByName(name string) *Project {
 	return nil
 }
 
-// InitConfig 初始化配置，如果配置文件不存在则创建默认配置
-func InitConfig() {
-	config, err := LoadConfig("config.yaml")
+// InitConfig 加载配置文件，配置无效时返回错误（不再回退到默认配置）
+func InitConfig() error {
+	config, err := LoadConfig(configPath)
 	if err != nil {
-		log.Printf("加载配置文件失败: %v", err)
-		log.Println("使用默认配置...")
-
-		// 创建默认配置
-		config = &Config{
-			Server: ServerConfig{
-				Port: 8080,
-				Host: "0.0.0.0",
-			},
-			Auth: AuthConfig{
-				Username: "admin",
-				Password: "password",
-			},
-			Projects: []Project{
-				{
-					Name:        "当前项目",
-					Path:        "/www/wwwroot/gogo",
-					Description: "当前版本管理工具项目",
-					Enabled:     true,
-				},
-			},
-			UI: UIConfig{
-				Title:    "Git 版本管理工具",
-				Theme:    "default",
-				Language: "zh-CN",
-			},
-			Security: SecurityConfig{
-				EnableAuth:     true,
-				SessionTimeout: 3600,
-				SessionSecret:  "gogo-version-manager-secret-key-2024",
-				RememberMeDays: 7,
-			},
-			Logging: LoggingConfig{
-				Level: "info",
-				File:  "logs/app.log",
-			},
-		}
-		AppConfig = config
+		return fmt.Errorf("加载配置文件 %s 失败: %v", configPath, err)
 	}
 
 	log.Printf("配置加载成功，共有 %d 个项目", len(config.Projects))
 
 	// 创建 Beego 配置文件
 	createBeegoConfig()
+	return nil
 }
 
 // createBeegoConfig 创建 Beego 配置文件
@@ -197,7 +381,7 @@ enablestatic = false
 # 日志配置
 [logs]
 level = %s
-`, AppConfig.Server.Port, AppConfig.Logging.Level)
+`, GetConfig().Server.Port, GetConfig().Logging.Level)
 
 		// 写入文件
 		err := os.WriteFile(configFile, []byte(configContent), 0600) // 更严格的文件权限
@@ -0,0 +1,263 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configWriteMu 串行化对配置文件的修改
+var configWriteMu sync.Mutex
+
+// updateConfigFile 在 YAML 节点树上修改配置文件：保留注释和未修改的内容，校验通过后原子写入并立即生效
+func updateConfigFile(edit func(root *yaml.Node) error) error {
+	configWriteMu.Lock()
+	defer configWriteMu.Unlock()
+
+	path := ConfigPath()
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("解析配置文件失败: %v", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("配置文件顶层必须是映射")
+	}
+	if err := edit(root); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("生成配置文件失败: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("生成配置文件失败: %v", err)
+	}
+
+	// 修改后的配置必须通过完整校验才写入
+	config, err := parseConfigData(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), stat.Mode().Perm()); err != nil {
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	setConfig(config)
+	return nil
+}
+
+// SaveProject 保存项目的名称、路径、描述、启用状态、分组和环境；originalName 为空时新增项目
+// 钩子和健康检查等其他字段保持不变，项目改名时同步更新用户的项目授权
+func SaveProject(originalName string, project Project) error {
+	return updateConfigFile(func(root *yaml.Node) error {
+		projects := mappingSequence(root, "projects")
+		if originalName == "" || originalName != project.Name {
+			if findProjectNode(projects, project.Name) != nil {
+				return fmt.Errorf("项目 %s 已存在", project.Name)
+			}
+		}
+
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if originalName != "" {
+			if node = findProjectNode(projects, originalName); node == nil {
+				return fmt.Errorf("项目 %s 不存在", originalName)
+			}
+		} else {
+			projects.Content = append(projects.Content, node)
+		}
+
+		setMappingScalar(node, "name", project.Name)
+		setMappingScalar(node, "path", project.Path)
+		setMappingScalar(node, "description", project.Description)
+		setMappingBool(node, "enabled", project.Enabled)
+		setOptionalScalar(node, "group", project.Group)
+		setOptionalScalar(node, "environment", project.Environment)
+		setOptionalScalar(node, "git_backend", project.GitBackend)
+		setOptionalScalar(node, "dirty_policy", project.DirtyPolicy)
+
+		if originalName != "" && originalName != project.Name {
+			renameProjectGrants(root, originalName, project.Name)
+		}
+		return nil
+	})
+}
+
+// SetProjectEnabled 启用或禁用项目
+func SetProjectEnabled(name string, enabled bool) error {
+	return updateConfigFile(func(root *yaml.Node) error {
+		node := findProjectNode(mappingSequence(root, "projects"), name)
+		if node == nil {
+			return fmt.Errorf("项目 %s 不存在", name)
+		}
+		setMappingBool(node, "enabled", enabled)
+		return nil
+	})
+}
+
+// DeleteProject 删除项目，仍被用户授权引用的项目不能删除（删除授权会让该用户获得全部项目的权限）
+func DeleteProject(name string) error {
+	return updateConfigFile(func(root *yaml.Node) error {
+		if users := projectGrantUsers(root, name); len(users) > 0 {
+			return fmt.Errorf("用户 %s 的项目授权引用了项目 %s，请先修改用户配置", strings.Join(users, "、"), name)
+		}
+
+		projects := mappingSequence(root, "projects")
+		for i, node := range projects.Content {
+			if scalarValue(node, "name") == name {
+				projects.Content = append(projects.Content[:i], projects.Content[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("项目 %s 不存在", name)
+	})
+}
+
+// mappingValue 返回映射中指定键的值节点
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarValue 返回映射中指定键的标量值
+func scalarValue(mapping *yaml.Node, key string) string {
+	if value := mappingValue(mapping, key); value != nil && value.Kind == yaml.ScalarNode {
+		return value.Value
+	}
+	return ""
+}
+
+// mappingSequence 返回映射中指定键的序列节点，不存在或为空时创建
+func mappingSequence(mapping *yaml.Node, key string) *yaml.Node {
+	value := mappingValue(mapping, key)
+	if value == nil {
+		value = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	} else if value.Kind != yaml.SequenceNode {
+		// projects: 留空时是 null 标量，转换为序列
+		*value = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", HeadComment: value.HeadComment, LineComment: value.LineComment}
+	}
+	return value
+}
+
+// setMappingScalar 设置字符串值，保留原有的引号风格和注释，新增的值使用双引号
+func setMappingScalar(mapping *yaml.Node, key, value string) {
+	if node := mappingValue(mapping, key); node != nil {
+		node.Kind = yaml.ScalarNode
+		node.Tag = "!!str"
+		node.Value = value
+		node.Content = nil
+		return
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: yaml.DoubleQuotedStyle})
+}
+
+// setOptionalScalar 设置可选的字符串值，值为空时删除该键
+func setOptionalScalar(mapping *yaml.Node, key, value string) {
+	if value != "" {
+		setMappingScalar(mapping, key, value)
+		return
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// setMappingBool 设置布尔值
+func setMappingBool(mapping *yaml.Node, key string, value bool) {
+	if node := mappingValue(mapping, key); node != nil {
+		node.Kind = yaml.ScalarNode
+		node.Tag = "!!bool"
+		node.Value = strconv.FormatBool(value)
+		node.Style = 0
+		node.Content = nil
+		return
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)})
+}
+
+// findProjectNode 按名称查找项目节点
+func findProjectNode(projects *yaml.Node, name string) *yaml.Node {
+	for _, node := range projects.Content {
+		if scalarValue(node, "name") == name {
+			return node
+		}
+	}
+	return nil
+}
+
+// userGrantNodes 遍历所有用户的项目授权节点
+func userGrantNodes(root *yaml.Node, visit func(username string, grant *yaml.Node)) {
+	users := mappingValue(root, "users")
+	if users == nil || users.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, user := range users.Content {
+		grants := mappingValue(user, "projects")
+		if grants == nil || grants.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, grant := range grants.Content {
+			visit(scalarValue(user, "username"), grant)
+		}
+	}
+}
+
+// projectGrantUsers 返回项目授权中引用了指定项目的用户
+func projectGrantUsers(root *yaml.Node, project string) []string {
+	var users []string
+	userGrantNodes(root, func(username string, grant *yaml.Node) {
+		if scalarValue(grant, "project") == project {
+			users = append(users, username)
+		}
+	})
+	return users
+}
+
+// renameProjectGrants 项目改名后同步更新用户的项目授权
+func renameProjectGrants(root *yaml.Node, oldName, newName string) {
+	userGrantNodes(root, func(_ string, grant *yaml.Node) {
+		if scalarValue(grant, "project") == oldName {
+			setMappingScalar(grant, "project", newName)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 部署结果
+const (
+	HistoryResultSuccess    = "success"
+	HistoryResultFailed     = "failed"
+	HistoryResultRolledBack = "rolled_back"
+)
+
+// HistoryStep 部署过程中执行的单个步骤
+type HistoryStep struct {
+	Phase    string `json:"phase"`
+	Name     string `json:"name"`
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration_ms"`
+}
+
+// HistoryEntry 部署历史记录，每次检出操作追加一条
+type HistoryEntry struct {
+	ID         string        `json:"id"`
+	Time       time.Time     `json:"time"`
+	User       string        `json:"user"`
+	Project    string        `json:"project"`
+	Action     string        `json:"action"`      // 操作类型：checkout 或 revert
+	TargetType string        `json:"target_type"` // "tag"、"branch" 或 "commit"
+	FromRef    string        `json:"from_ref"`
+	FromCommit string        `json:"from_commit"`
+	FromBranch string        `json:"from_branch,omitempty"`
+	ToRef      string        `json:"to_ref"`
+	ToCommit   string        `json:"to_commit"`
+	Result     string        `json:"result"` // success / failed / rolled_back
+	Message    string        `json:"message"`
+	Duration   int64         `json:"duration_ms"`
+	Steps      []HistoryStep `json:"steps,omitempty"`
+}
+
+// HistoryFilter 历史记录查询条件
+type HistoryFilter struct {
+	Project string
+	User    string
+	Result  string
+	Limit   int // 最多返回的条数，0 表示不限制
+}
+
+// historyMutex 保证同一进程内追加写入的顺序
+var historyMutex sync.Mutex
+
+// historyFile 返回历史记录文件路径
+func historyFile() string {
+	return GetConfig().DataPath("history.jsonl")
+}
+
+// newHistoryID 生成历史记录 ID
+func newHistoryID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// AppendHistory 追加一条部署历史记录（JSON Lines 格式，只追加不修改）
+func AppendHistory(entry HistoryEntry) error {
+	if entry.ID == "" {
+		entry.ID = newHistoryID()
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("序列化历史记录失败: %v", err)
+	}
+
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	path := historyFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("打开历史记录文件失败: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入历史记录失败: %v", err)
+	}
+	return nil
+}
+
+// QueryHistory 按条件查询部署历史，结果按时间倒序排列
+func QueryHistory(filter HistoryFilter) ([]HistoryEntry, error) {
+	file, err := os.Open(historyFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []HistoryEntry{}, nil
+		}
+		return nil, fmt.Errorf("打开历史记录文件失败: %v", err)
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // 单条记录可能包含较多命令输出
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // 跳过损坏的行
+		}
+		if filter.Project != "" && entry.Project != filter.Project {
+			continue
+		}
+		if filter.User != "" && entry.User != filter.User {
+			continue
+		}
+		if filter.Result != "" && entry.Result != filter.Result {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取历史记录失败: %v", err)
+	}
+
+	// 文件按时间顺序追加，倒序即为最新在前
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	if entries == nil {
+		entries = []HistoryEntry{}
+	}
+	return entries, nil
+}
+
+// LastSuccessfulDeploy 返回项目最近一次成功的部署记录，没有记录时返回 nil
+// 记录中的 FromCommit/FromBranch 即为该次部署之前的版本
+func LastSuccessfulDeploy(project string) (*HistoryEntry, error) {
+	entries, err := QueryHistory(HistoryFilter{
+		Project: project,
+		Result:  HistoryResultSuccess,
+		Limit:   1,
+	})
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPattern 匹配 ${VAR}、${VAR:-默认值} 以及转义的 $${
+var envPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// hookStepKeys 检出步骤列表的键名
+var hookStepKeys = map[string]bool{"pre_checkout": true, "post_checkout": true}
+
+// hookRuntimeKeys 检出步骤中在运行时展开的字段：command 由 shell 展开，env 在执行步骤时展开，
+// 可以引用 gover 注入的 GOVER_TARGET_REF 等变量，加载配置时不展开
+var hookRuntimeKeys = map[string]bool{"command": true, "env": true}
+
+// expandEnvNodes 展开所有标量值中的环境变量引用（不展开键名和检出步骤的 command、env），未设置且没有默认值的变量作为问题返回
+func expandEnvNodes(node *yaml.Node, problems *ValidationErrors) {
+	if node.Kind == yaml.MappingNode {
+		for i := 1; i < len(node.Content); i += 2 {
+			if hookStepKeys[node.Content[i-1].Value] && node.Content[i].Kind == yaml.SequenceNode {
+				for _, step := range node.Content[i].Content {
+					expandHookStepNode(step, problems)
+				}
+				continue
+			}
+			expandEnvNodes(node.Content[i], problems)
+		}
+		return
+	}
+	if node.Kind == yaml.ScalarNode {
+		value, missing := expandEnv(node.Value)
+		for _, name := range missing {
+			*problems = append(*problems, FieldError{Line: node.Line, Message: fmt.Sprintf("环境变量 %s 未设置", name)})
+		}
+		if value != node.Value {
+			node.Value = value
+			if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				// 未加引号的值按展开后的内容重新推断类型，如 port: ${PORT}
+				node.Tag = ""
+			}
+		}
+		return
+	}
+	for _, child := range node.Content {
+		expandEnvNodes(child, problems)
+	}
+}
+
+// expandHookStepNode 展开检出步骤中除 command、env 以外的字段
+func expandHookStepNode(node *yaml.Node, problems *ValidationErrors) {
+	if node.Kind != yaml.MappingNode {
+		expandEnvNodes(node, problems)
+		return
+	}
+	for i := 1; i < len(node.Content); i += 2 {
+		if !hookRuntimeKeys[node.Content[i-1].Value] {
+			expandEnvNodes(node.Content[i], problems)
+		}
+	}
+}
+
+// expandEnv 展开字符串中的环境变量引用，返回未设置的变量名
+func expandEnv(value string) (string, []string) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+	var missing []string
+	expanded := envPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+		match := envPattern.FindStringSubmatch(ref)
+		if env, ok := os.LookupEnv(match[1]); ok && env != "" {
+			return env
+		}
+		if match[2] != "" {
+			return match[3]
+		}
+		missing = append(missing, match[1])
+		return ""
+	})
+	return expanded, missing
+}
+
+// resolveSecretFiles 读取 *_file 配置的密钥文件，内容去掉首尾空白后填入对应字段
+func (c *Config) resolveSecretFiles() ValidationErrors {
+	var problems ValidationErrors
+	readSecretFile(&problems, "auth", "password", &c.Auth.Password, c.Auth.PasswordFile)
+	for i := range c.Users {
+		user := &c.Users[i]
+		readSecretFile(&problems, fmt.Sprintf("users[%d]", i), "password", &user.Password, user.PasswordFile)
+	}
+	readSecretFile(&problems, "security", "session_secret", &c.Security.SessionSecret, c.Security.SessionSecretFile)
+	return problems
+}
+
+// readSecretFile 从文件读取单个密钥，不能与直接配置的值同时使用
+func readSecretFile(problems *ValidationErrors, prefix, name string, value *string, path string) {
+	if path == "" {
+		return
+	}
+	field := prefix + "." + name + "_file"
+	if *value != "" {
+		problems.add(field, "不能与 %s.%s 同时配置", prefix, name)
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		problems.add(field, "读取失败: %v", err)
+		return
+	}
+	*value = strings.TrimSpace(string(data))
+	if *value == "" {
+		problems.add(field, "文件 %s 为空", path)
+	}
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 支持的密码哈希算法
+const (
+	PasswordAlgoBcrypt   = "bcrypt"
+	PasswordAlgoArgon2id = "argon2id"
+)
+
+// argon2id 参数（参考 OWASP 推荐值）
+const (
+	argon2Memory  = 64 * 1024 // KiB
+	argon2Time    = 3
+	argon2Threads = 2
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// ErrUnsupportedPasswordHash 密码字段不是受支持的哈希（通常是明文密码）
+var ErrUnsupportedPasswordHash = errors.New("密码不是 bcrypt 或 argon2id 哈希，请使用 gover hash-password 生成")
+
+// dummyPasswordHash 用户不存在时用于比较的哈希，使登录耗时与用户是否存在无关
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("gover-dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// HashPassword 使用指定算法生成密码哈希，algo 为空时使用 bcrypt
+func HashPassword(password, algo string) (string, error) {
+	switch algo {
+	case "", PasswordAlgoBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", fmt.Errorf("生成 bcrypt 哈希失败: %v", err)
+		}
+		return string(hash), nil
+
+	case PasswordAlgoArgon2id:
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("生成随机盐失败: %v", err)
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key)), nil
+
+	default:
+		return "", fmt.Errorf("不支持的哈希算法: %s（可选 bcrypt、argon2id）", algo)
+	}
+}
+
+// IsPasswordHash 判断字符串是否为受支持的密码哈希格式
+func IsPasswordHash(value string) bool {
+	return isBcryptHash(value) || strings.HasPrefix(value, "$argon2id$")
+}
+
+// isBcryptHash 判断是否为 bcrypt 哈希
+func isBcryptHash(value string) bool {
+	return strings.HasPrefix(value, "$2a$") || strings.HasPrefix(value, "$2b$") || strings.HasPrefix(value, "$2y$")
+}
+
+// VerifyPassword 以常量时间校验密码是否与哈希匹配
+// 哈希格式不受支持（如明文密码）时返回 ErrUnsupportedPasswordHash
+func VerifyPassword(hash, password string) (bool, error) {
+	switch {
+	case isBcryptHash(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("bcrypt 哈希无效: %v", err)
+		}
+		return true, nil
+
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2id(hash, password)
+
+	default:
+		return false, ErrUnsupportedPasswordHash
+	}
+}
+
+// verifyArgon2id 校验 PHC 格式的 argon2id 哈希：$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func verifyArgon2id(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, errors.New("argon2id 哈希格式无效")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, fmt.Errorf("不支持的 argon2id 版本: %s", parts[2])
+	}
+
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false, fmt.Errorf("argon2id 参数无效: %v", err)
+	}
+	if memory == 0 || iterations == 0 || threads == 0 {
+		return false, errors.New("argon2id 参数无效: m、t、p 必须大于 0")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, fmt.Errorf("argon2id 盐值无效: %v", err)
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, fmt.Errorf("argon2id 哈希值无效: %v", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
+
+// CheckUserPassword 校验用户名和密码，用户不存在时同样执行一次哈希比较以避免时序差异
+func (c *Config) CheckUserPassword(username, password string) (*UserConfig, error) {
+	user := c.FindUser(username)
+	if user == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, nil
+	}
+
+	ok, err := VerifyPassword(user.Password, password)
+	if err != nil {
+		return nil, fmt.Errorf("用户 %s: %v", username, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return user, nil
+}
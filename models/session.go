@@ -0,0 +1,262 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionTouchInterval 最近活动时间的持久化间隔，避免每个请求都写文件
+const sessionTouchInterval = time.Minute
+
+// MaxSessionRecords 最多保存的会话数量，超出时删除最近活动时间最早的会话
+var MaxSessionRecords = 10000
+
+// SessionRecord 服务端保存的会话
+type SessionRecord struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username,omitempty"` // 已登录的用户名
+	IP           string    `json:"ip"`
+	UserAgent    string    `json:"user_agent"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActivity time.Time `json:"last_activity"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Data         string    `json:"data"` // 编码并签名后的会话数据
+}
+
+// Expired 会话是否已过期
+func (r *SessionRecord) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt)
+}
+
+// ShortID 用于展示的会话 ID 前缀
+func (r *SessionRecord) ShortID() string {
+	if len(r.ID) > 12 {
+		return r.ID[:12]
+	}
+	return r.ID
+}
+
+// SessionFilePath 返回会话文件路径
+func (c *Config) SessionFilePath() string {
+	return c.DataPath("sessions.json")
+}
+
+// SessionStore 基于文件的会话存储，文件被其他进程（如命令行）修改后自动重新加载
+type SessionStore struct {
+	path      string
+	mu        sync.Mutex
+	records   map[string]*SessionRecord
+	modTime   time.Time
+	size      int64
+	persisted map[string]time.Time // 每个会话最近一次持久化活动时间的时刻
+}
+
+// NewSessionStore 创建会话存储
+func NewSessionStore(path string) *SessionStore {
+	return &SessionStore{
+		path:      path,
+		records:   map[string]*SessionRecord{},
+		persisted: map[string]time.Time{},
+	}
+}
+
+// reload 文件发生变化时重新加载，调用方需持有锁
+func (s *SessionStore) reload() error {
+	stat, err := os.Stat(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.records = map[string]*SessionRecord{}
+			s.modTime, s.size = time.Time{}, 0
+			return nil
+		}
+		return err
+	}
+	if stat.ModTime().Equal(s.modTime) && stat.Size() == s.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("读取会话文件失败: %v", err)
+	}
+	var list []SessionRecord
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &list); err != nil {
+			return fmt.Errorf("解析会话文件 %s 失败: %v", s.path, err)
+		}
+	}
+
+	s.records = make(map[string]*SessionRecord, len(list))
+	for i := range list {
+		s.records[list[i].ID] = &list[i]
+	}
+	s.modTime, s.size = stat.ModTime(), stat.Size()
+	return nil
+}
+
+// persist 清理过期会话并原子写入文件（权限 0600），调用方需持有锁
+// 旧版本为未登录请求保存的会话（用户名为空）同时被清理
+func (s *SessionStore) persist() error {
+	s.evict(-1)
+	list := make([]SessionRecord, 0, len(s.records))
+	for _, record := range s.records {
+		list = append(list, *record)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化会话失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0750); err != nil {
+		return fmt.Errorf("创建会话目录失败: %v", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入会话文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入会话文件失败: %v", err)
+	}
+
+	if stat, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = stat.ModTime(), stat.Size()
+	}
+	return nil
+}
+
+// Get 获取未过期的会话
+func (s *SessionStore) Get(id string) (*SessionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	record, ok := s.records[id]
+	if !ok || record.Expired(time.Now()) {
+		return nil, nil
+	}
+	copied := *record
+	return &copied, nil
+}
+
+// Put 保存会话
+func (s *SessionStore) Put(record SessionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return err
+	}
+	if _, ok := s.records[record.ID]; !ok {
+		s.evict(MaxSessionRecords - 1)
+	}
+	s.records[record.ID] = &record
+	s.persisted[record.ID] = time.Now()
+	return s.persist()
+}
+
+// evict 删除过期会话和未登录的会话，limit 不小于 0 且仍超过 limit 个时按最近活动时间从早到晚删除，调用方需持有锁
+func (s *SessionStore) evict(limit int) {
+	now := time.Now()
+	for id, record := range s.records {
+		if record.Expired(now) || record.Username == "" {
+			delete(s.records, id)
+			delete(s.persisted, id)
+		}
+	}
+	if limit < 0 || len(s.records) <= limit {
+		return
+	}
+
+	list := make([]*SessionRecord, 0, len(s.records))
+	for _, record := range s.records {
+		list = append(list, record)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastActivity.Before(list[j].LastActivity) })
+	for _, record := range list[:len(list)-limit] {
+		delete(s.records, record.ID)
+		delete(s.persisted, record.ID)
+	}
+}
+
+// Touch 更新会话的最近活动时间、IP 和 User-Agent，按间隔持久化
+func (s *SessionStore) Touch(id, ip, userAgent string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return err
+	}
+	record, ok := s.records[id]
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	changed := record.IP != ip || record.UserAgent != userAgent
+	record.LastActivity = now
+	record.IP = ip
+	record.UserAgent = userAgent
+
+	if changed || now.Sub(s.persisted[id]) >= sessionTouchInterval {
+		s.persisted[id] = now
+		return s.persist()
+	}
+	return nil
+}
+
+// Delete 删除满足条件的会话，返回删除的数量
+func (s *SessionStore) Delete(match func(record *SessionRecord) bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return 0, err
+	}
+	count := 0
+	for id, record := range s.records {
+		if match(record) {
+			delete(s.records, id)
+			delete(s.persisted, id)
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return count, s.persist()
+}
+
+// List 返回所有已登录且未过期的会话，按最近活动时间倒序
+func (s *SessionStore) List() ([]SessionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	list := []SessionRecord{}
+	for _, record := range s.records {
+		if record.Username != "" && !record.Expired(now) {
+			list = append(list, *record)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastActivity.After(list[j].LastActivity) })
+	return list, nil
+}
+
+// MatchSessionID 判断会话 ID 是否匹配完整 ID 或 ID 前缀
+func MatchSessionID(record *SessionRecord, id string) bool {
+	return id != "" && strings.HasPrefix(record.ID, id)
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// sessionKeyLength 自动生成的会话密钥长度（字节）
+const sessionKeyLength = 64
+
+// knownDefaultSessionSecrets 历史版本配置和文档中出现过的公开默认密钥，使用这些值等同于没有密钥
+var knownDefaultSessionSecrets = []string{
+	"gogo-version-manager-secret-key-2024",
+	"gover-secret-key-2024",
+	"gover-secret-key",
+}
+
+// SessionKey 自动生成的会话签名密钥
+type SessionKey struct {
+	Key       string    `json:"key"` // base64 编码
+	CreatedAt time.Time `json:"created_at"`
+	RetiredAt time.Time `json:"retired_at"` // 轮换时间，之后只用于验证轮换前签发的会话
+}
+
+// sessionKeyFileMu 保护密钥文件的生成和轮换
+var sessionKeyFileMu sync.Mutex
+
+// IsDefaultSessionSecret 是否为公开的默认密钥
+func IsDefaultSessionSecret(secret string) bool {
+	for _, known := range knownDefaultSessionSecrets {
+		if secret == known {
+			return true
+		}
+	}
+	return false
+}
+
+// GeneratesSessionSecret 是否使用自动生成的会话密钥（未配置 session_secret 或使用了公开的默认值）
+func (c *Config) GeneratesSessionSecret() bool {
+	return c.Security.SessionSecret == "" || IsDefaultSessionSecret(c.Security.SessionSecret)
+}
+
+// SessionKeyFilePath 返回自动生成的会话密钥文件路径
+func (c *Config) SessionKeyFilePath() string {
+	return c.DataPath("session_keys.json")
+}
+
+// sessionKeyGracePeriod 轮换后旧密钥的保留时间，取最长的会话有效期，保证已登录的会话自然过期前不受影响
+func (c *Config) sessionKeyGracePeriod() time.Duration {
+	grace := time.Duration(c.Security.SessionTimeout) * time.Second
+	if remember := time.Duration(c.Security.RememberMeDays) * 24 * time.Hour; remember > grace {
+		grace = remember
+	}
+	if anonymous := 24 * time.Hour; anonymous > grace {
+		grace = anonymous
+	}
+	return grace
+}
+
+// SessionKeys 返回会话签名密钥，第一个用于签名新会话，其余只用于验证轮换前签发的会话
+// 配置了 session_secret 时使用配置的密钥和 previous_session_secrets；否则使用自动生成的密钥，首次调用时生成
+func (c *Config) SessionKeys() ([][]byte, error) {
+	if !c.GeneratesSessionSecret() {
+		keys := [][]byte{[]byte(c.Security.SessionSecret)}
+		for _, previous := range c.Security.PreviousSessionSecrets {
+			keys = append(keys, []byte(previous))
+		}
+		return keys, nil
+	}
+
+	sessionKeyFileMu.Lock()
+	defer sessionKeyFileMu.Unlock()
+
+	path := c.SessionKeyFilePath()
+	stored, err := loadSessionKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) == 0 {
+		key, err := newSessionKey()
+		if err != nil {
+			return nil, err
+		}
+		stored = []SessionKey{key}
+		if err := saveSessionKeyFile(path, stored); err != nil {
+			return nil, err
+		}
+		fmt.Printf("🔑 已生成随机会话密钥并保存到 %s（权限 0600）\n", path)
+	}
+
+	now := time.Now()
+	grace := c.sessionKeyGracePeriod()
+	var keys [][]byte
+	for _, key := range stored {
+		if !key.RetiredAt.IsZero() && now.Sub(key.RetiredAt) > grace {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(key.Key)
+		if err != nil {
+			return nil, fmt.Errorf("会话密钥文件 %s 已损坏: %v", path, err)
+		}
+		keys = append(keys, decoded)
+	}
+	return keys, nil
+}
+
+// SessionKeysModTime 返回自动生成的会话密钥文件的修改时间，用于发现命令行轮换了密钥
+func (c *Config) SessionKeysModTime() time.Time {
+	if !c.GeneratesSessionSecret() {
+		return time.Time{}
+	}
+	stat, err := os.Stat(c.SessionKeyFilePath())
+	if err != nil {
+		return time.Time{}
+	}
+	return stat.ModTime()
+}
+
+// RotateSessionKey 生成新的会话密钥，旧密钥保留到已签发的会话全部过期，返回旧密钥的保留时间
+func (c *Config) RotateSessionKey() (time.Duration, error) {
+	if !c.GeneratesSessionSecret() {
+		return 0, fmt.Errorf("session_secret 在配置文件中设置，请手动轮换：把旧值移到 security.previous_session_secrets，再设置新的 session_secret")
+	}
+
+	sessionKeyFileMu.Lock()
+	defer sessionKeyFileMu.Unlock()
+
+	path := c.SessionKeyFilePath()
+	stored, err := loadSessionKeyFile(path)
+	if err != nil {
+		return 0, err
+	}
+	key, err := newSessionKey()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	grace := c.sessionKeyGracePeriod()
+	rotated := []SessionKey{key}
+	for _, old := range stored {
+		if old.RetiredAt.IsZero() {
+			old.RetiredAt = now
+		}
+		if now.Sub(old.RetiredAt) <= grace {
+			rotated = append(rotated, old)
+		}
+	}
+	return grace, saveSessionKeyFile(path, rotated)
+}
+
+// newSessionKey 生成随机密钥
+func newSessionKey() (SessionKey, error) {
+	buf := make([]byte, sessionKeyLength)
+	if _, err := rand.Read(buf); err != nil {
+		return SessionKey{}, fmt.Errorf("生成会话密钥失败: %v", err)
+	}
+	return SessionKey{Key: base64.StdEncoding.EncodeToString(buf), CreatedAt: time.Now()}, nil
+}
+
+// loadSessionKeyFile 读取密钥文件，文件不存在时返回空列表
+func loadSessionKeyFile(path string) ([]SessionKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取会话密钥文件失败: %v", err)
+	}
+	var keys []SessionKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("解析会话密钥文件 %s 失败: %v", path, err)
+	}
+	return keys, nil
+}
+
+// saveSessionKeyFile 原子写入密钥文件（权限 0600）
+func saveSessionKeyFile(path string, keys []SessionKey) error {
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化会话密钥失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入会话密钥文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入会话密钥文件失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APITokenPrefix API 令牌前缀，便于识别和扫描泄露
+const APITokenPrefix = "gvr_"
+
+// APIToken API 令牌配置，只保存令牌的 SHA-256 哈希
+type APIToken struct {
+	Name      string    `yaml:"name"`
+	Hash      string    `yaml:"hash"`                 // 令牌的 SHA-256 十六进制哈希
+	Projects  []string  `yaml:"projects,omitempty"`   // 允许访问的项目，为空表示全部项目
+	ReadOnly  bool      `yaml:"read_only,omitempty"`  // 只读令牌不能执行检出和回退
+	Revoked   bool      `yaml:"revoked,omitempty"`    // 已吊销的令牌不再被接受
+	CreatedAt time.Time `yaml:"created_at,omitempty"` // 创建时间
+}
+
+// AllowsProject 检查令牌是否可以访问指定项目
+func (t *APIToken) AllowsProject(name string) bool {
+	if len(t.Projects) == 0 {
+		return true
+	}
+	for _, project := range t.Projects {
+		if project == name {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenFileCache 令牌文件缓存，文件修改后自动重新加载，便于命令行吊销令牌后立即生效
+var tokenFileCache struct {
+	sync.Mutex
+	path    string
+	modTime time.Time
+	tokens  []APIToken
+}
+
+// GenerateAPIToken 生成新的随机令牌，返回明文令牌及其哈希
+func GenerateAPIToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("生成随机令牌失败: %v", err)
+	}
+	token := APITokenPrefix + hex.EncodeToString(buf)
+	return token, HashAPIToken(token), nil
+}
+
+// HashAPIToken 计算令牌的 SHA-256 哈希
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenFilePath 返回 API 令牌文件路径
+func (c *Config) TokenFilePath() string {
+	if c.Security.TokenFile != "" {
+		return c.Security.TokenFile
+	}
+	return c.DataPath("tokens.yaml")
+}
+
+// LoadTokenFile 读取令牌文件，文件不存在时返回空列表
+func LoadTokenFile(path string) ([]APIToken, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var tokens []APIToken
+	if err := yaml.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("解析令牌文件 %s 失败: %v", path, err)
+	}
+	return tokens, nil
+}
+
+// SaveTokenFile 原子写入令牌文件（权限 0600）
+func SaveTokenFile(path string, tokens []APIToken) error {
+	data, err := yaml.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("序列化令牌失败: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("创建令牌目录失败: %v", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入令牌文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入令牌文件失败: %v", err)
+	}
+	return nil
+}
+
+// fileTokens 返回令牌文件中的令牌，文件未修改时使用缓存
+func (c *Config) fileTokens() []APIToken {
+	path := c.TokenFilePath()
+
+	tokenFileCache.Lock()
+	defer tokenFileCache.Unlock()
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		tokenFileCache.path = path
+		tokenFileCache.modTime = time.Time{}
+		tokenFileCache.tokens = nil
+		return nil
+	}
+
+	if tokenFileCache.path == path && tokenFileCache.modTime.Equal(stat.ModTime()) {
+		return tokenFileCache.tokens
+	}
+
+	tokens, err := LoadTokenFile(path)
+	if err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+		return tokenFileCache.tokens
+	}
+	tokenFileCache.path = path
+	tokenFileCache.modTime = stat.ModTime()
+	tokenFileCache.tokens = tokens
+	return tokens
+}
+
+// FindAPIToken 根据明文令牌查找有效（未吊销）的令牌配置
+// 同一哈希出现多次时，只要其中一条已吊销就拒绝，避免吊销被前面的重复条目绕过
+func (c *Config) FindAPIToken(token string) *APIToken {
+	if !strings.HasPrefix(token, APITokenPrefix) {
+		return nil
+	}
+	hash := []byte(HashAPIToken(token))
+
+	candidates := append([]APIToken{}, c.APITokens...)
+	candidates = append(candidates, c.fileTokens()...)
+
+	var found *APIToken
+	revoked := false
+	for i := range candidates {
+		// 逐个进行常量时间比较，不提前退出
+		if subtle.ConstantTimeCompare(hash, []byte(strings.ToLower(candidates[i].Hash))) != 1 {
+			continue
+		}
+		if candidates[i].Revoked {
+			revoked = true
+		}
+		if found == nil {
+			found = &candidates[i]
+		}
+	}
+	if found == nil || revoked {
+		return nil
+	}
+	return found
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFindAPIToken(t *testing.T) {
+	valid, validHash, _ := GenerateAPIToken()
+	revoked, revokedHash, _ := GenerateAPIToken()
+	duplicated, duplicatedHash, _ := GenerateAPIToken()
+	fileRevoked, fileRevokedHash, _ := GenerateAPIToken()
+	unknown, _, _ := GenerateAPIToken()
+
+	// 令牌文件中的条目可能与配置文件中的哈希重复，其中一条已吊销时令牌应被拒绝
+	tokenFile := filepath.Join(t.TempDir(), "tokens.yaml")
+	if err := SaveTokenFile(tokenFile, []APIToken{
+		{Name: "file-ci", Hash: fileRevokedHash, Revoked: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{
+		Security: SecurityConfig{TokenFile: tokenFile},
+		APITokens: []APIToken{
+			{Name: "ci", Hash: validHash},
+			{Name: "old", Hash: revokedHash, Revoked: true},
+			{Name: "dup", Hash: duplicatedHash},
+			{Name: "dup-revoked", Hash: duplicatedHash, Revoked: true},
+			{Name: "ci-copy", Hash: fileRevokedHash},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  string // 期望找到的令牌名称，空字符串表示拒绝
+	}{
+		{"有效令牌", valid, "ci"},
+		{"已吊销的令牌", revoked, ""},
+		{"重复哈希中有一条已吊销", duplicated, ""},
+		{"令牌文件中的重复条目已吊销", fileRevoked, ""},
+		{"未知令牌", unknown, ""},
+		{"缺少前缀", valid[len(APITokenPrefix):], ""},
+		{"空令牌", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := config.FindAPIToken(tt.token)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("令牌应被拒绝，实际找到 %s", got.Name)
+			case tt.want != "" && (got == nil || got.Name != tt.want):
+				t.Errorf("期望找到令牌 %s，实际为 %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAPITokenAllowsProject(t *testing.T) {
+	tests := []struct {
+		name     string
+		projects []string
+		project  string
+		want     bool
+	}{
+		{"未限制项目", nil, "app", true},
+		{"在允许的项目中", []string{"app", "web"}, "web", true},
+		{"不在允许的项目中", []string{"app"}, "web", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := APIToken{Name: "ci", Projects: tt.projects}
+			if got := token.AllowsProject(tt.project); got != tt.want {
+				t.Errorf("AllowsProject(%q) = %v，期望 %v", tt.project, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package models
+
+// 用户角色，权限依次递增
+const (
+	RoleViewer   = "viewer"   // 只能查看
+	RoleDeployer = "deployer" // 可以检出、回退、刷新
+	RoleAdmin    = "admin"    // 全部权限，包括管理功能
+)
+
+// roleLevels 角色等级，用于比较权限高低
+var roleLevels = map[string]int{
+	RoleViewer:   1,
+	RoleDeployer: 2,
+	RoleAdmin:    3,
+}
+
+// ProjectGrant 用户对单个项目的授权
+type ProjectGrant struct {
+	Project string `yaml:"project"`
+	Role    string `yaml:"role"` // 为空时使用用户的全局角色
+}
+
+// UserConfig 用户配置
+type UserConfig struct {
+	Username     string         `yaml:"username"`
+	Password     string         `yaml:"password"`
+	PasswordFile string         `yaml:"password_file"` // 从文件读取密码哈希，与 password 二选一
+	Role         string         `yaml:"role"`          // viewer / deployer / admin，默认 viewer
+	Projects     []ProjectGrant `yaml:"projects"`      // 为空时角色作用于所有项目，否则只能访问列出的项目
+}
+
+// ValidRole 检查角色名称是否有效
+func ValidRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
+// RoleAtLeast 判断角色是否达到要求的最低角色
+func RoleAtLeast(role, required string) bool {
+	return roleLevels[role] >= roleLevels[required]
+}
+
+// GlobalRole 返回用户的全局角色，未配置时为 viewer
+func (u *UserConfig) GlobalRole() string {
+	if u.Role == "" {
+		return RoleViewer
+	}
+	return u.Role
+}
+
+// IsAdmin 是否为管理员
+func (u *UserConfig) IsAdmin() bool {
+	return u.GlobalRole() == RoleAdmin
+}
+
+// RoleFor 返回用户在指定项目上的角色，无权访问时返回空字符串
+func (u *UserConfig) RoleFor(project string) string {
+	if u.IsAdmin() || len(u.Projects) == 0 {
+		return u.GlobalRole()
+	}
+	for _, grant := range u.Projects {
+		if grant.Project == project {
+			if grant.Role == "" {
+				return u.GlobalRole()
+			}
+			return grant.Role
+		}
+	}
+	return ""
+}
+
+// AllUsers 返回所有用户；未配置 users 时，兼容旧版的单用户 auth 配置（视为管理员）
+func (c *Config) AllUsers() []UserConfig {
+	if len(c.Users) > 0 {
+		return c.Users
+	}
+	if c.Auth.Username == "" {
+		return nil
+	}
+	return []UserConfig{
+		{
+			Username: c.Auth.Username,
+			Password: c.Auth.Password,
+			Role:     RoleAdmin,
+		},
+	}
+}
+
+// FindUser 根据用户名查找用户
+func (c *Config) FindUser(username string) *UserConfig {
+	if username == "" {
+		return nil
+	}
+	for _, user := range c.AllUsers() {
+		if user.Username == username {
+			return &user
+		}
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// minSessionSecretLength session_secret 的最小长度
+const minSessionSecretLength = 16
+
+// FieldError 单个配置问题
+type FieldError struct {
+	Field   string // 字段路径，如 projects[0].path，YAML 解码错误时为空
+	Line    int    // YAML 解码错误所在行，未知时为 0
+	Message string
+}
+
+// Error 返回带字段路径或行号的错误信息
+func (e FieldError) Error() string {
+	switch {
+	case e.Field != "":
+		return e.Field + ": " + e.Message
+	case e.Line > 0:
+		return fmt.Sprintf("第 %d 行: %s", e.Line, e.Message)
+	default:
+		return e.Message
+	}
+}
+
+// ValidationErrors 配置中的全部问题
+type ValidationErrors []FieldError
+
+// Error 每行一个问题
+func (e ValidationErrors) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, fmt.Sprintf("配置中有 %d 个问题:", len(e)))
+	for _, problem := range e {
+		lines = append(lines, "  - "+problem.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// add 记录一个字段问题
+func (e *ValidationErrors) add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+var (
+	// yamlLinePattern 匹配 yaml.v3 解码错误中的行号前缀
+	yamlLinePattern = regexp.MustCompile(`^line (\d+): (.*)$`)
+	// yamlUnknownFieldPattern 匹配 yaml.v3 的未知字段错误
+	yamlUnknownFieldPattern = regexp.MustCompile(`^field (\S+) not found in type \S+$`)
+)
+
+// decodeConfig 解码 YAML 并展开环境变量引用：未知字段、未设置的环境变量和类型错误作为问题列表返回，语法错误直接返回 error
+func decodeConfig(data []byte, config *Config) (ValidationErrors, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("YAML 语法错误: %v", strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	if root.Kind == 0 {
+		// 空文件
+		return nil, nil
+	}
+
+	// 未知字段按原始内容严格检查，类型错误留到展开环境变量之后再检查
+	var problems ValidationErrors
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	for _, problem := range yamlProblems(decoder.Decode(&Config{})) {
+		if strings.HasPrefix(problem.Message, "未知字段") {
+			problems = append(problems, problem)
+		}
+	}
+
+	expandEnvNodes(&root, &problems)
+	problems = append(problems, yamlProblems(root.Decode(config))...)
+	return problems, nil
+}
+
+// yamlProblems 将 yaml.v3 的解码错误转换为带行号的问题列表
+func yamlProblems(err error) ValidationErrors {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return nil
+	}
+
+	var problems ValidationErrors
+	for _, message := range typeErr.Errors {
+		problem := FieldError{Message: message}
+		if match := yamlLinePattern.FindStringSubmatch(message); match != nil {
+			problem.Line, _ = strconv.Atoi(match[1])
+			problem.Message = match[2]
+		}
+		if match := yamlUnknownFieldPattern.FindStringSubmatch(problem.Message); match != nil {
+			problem.Message = "未知字段 " + match[1]
+		} else if strings.HasPrefix(problem.Message, "cannot unmarshal") {
+			problem.Message = "类型错误: " + problem.Message
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+// Validate 校验配置内容，返回 ValidationErrors 列出全部问题
+func (c *Config) Validate() error {
+	if problems := c.validate(); len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// Warnings 返回不影响加载配置的问题：已启用项目的路径不存在或不是目录，这些项目在运行时显示为不可用
+func (c *Config) Warnings() ValidationErrors {
+	var warnings ValidationErrors
+	for i, project := range c.Projects {
+		if project.Enabled && project.Path != "" {
+			if err := project.PathError(); err != nil {
+				warnings.add(fmt.Sprintf("projects[%d].path", i), "%v", err)
+			}
+		}
+	}
+	return warnings
+}
+
+// validate 逐项检查配置
+func (c *Config) validate() ValidationErrors {
+	var problems ValidationErrors
+	c.validateServer(&problems)
+	c.validateUsers(&problems)
+	c.validateProjects(&problems)
+	c.validateSecurity(&problems)
+	c.validateTokens(&problems)
+
+	switch strings.ToLower(c.Logging.Level) {
+	case "", "debug", "info", "warn", "warning", "error":
+	default:
+		problems.add("logging.level", "无效的日志级别 %q（可选 debug、info、warn、error）", c.Logging.Level)
+	}
+	return problems
+}
+
+// validateServer 检查监听和 TLS 配置
+func (c *Config) validateServer(problems *ValidationErrors) {
+	server := c.Server
+	if server.Port < 1 || server.Port > 65535 {
+		problems.add("server.port", "必须在 1-65535 之间，当前为 %d", server.Port)
+	}
+
+	tls := server.TLS
+	if _, err := tls.MinTLSVersion(); err != nil {
+		problems.add("server.tls.min_version", "%v", err)
+	}
+	if tls.RedirectPort < 0 || tls.RedirectPort > 65535 {
+		problems.add("server.tls.redirect_port", "必须在 0-65535 之间，当前为 %d", tls.RedirectPort)
+	} else if tls.RedirectPort != 0 && tls.RedirectPort == server.Port {
+		problems.add("server.tls.redirect_port", "不能与 server.port 相同")
+	}
+	if !tls.Enabled {
+		return
+	}
+	for _, file := range []struct{ field, path string }{
+		{"server.tls.cert_file", tls.CertFile},
+		{"server.tls.key_file", tls.KeyFile},
+	} {
+		if file.path == "" {
+			problems.add(file.field, "启用 TLS 时不能为空")
+		} else if _, err := os.Stat(file.path); err != nil {
+			problems.add(file.field, "文件不可读: %v", err)
+		}
+	}
+}
+
+// validateUsers 检查用户、密码哈希和项目授权
+func (c *Config) validateUsers(problems *ValidationErrors) {
+	if len(c.Users) == 0 {
+		if c.Auth.Username == "" {
+			problems.add("users", "至少需要配置一个用户（或旧版 auth.username / auth.password）")
+			return
+		}
+		validatePasswordField(problems, "auth.password", c.Auth.Password)
+		return
+	}
+
+	projectNames := map[string]bool{}
+	for _, project := range c.Projects {
+		projectNames[project.Name] = true
+	}
+
+	usernames := map[string]bool{}
+	for i, user := range c.Users {
+		field := fmt.Sprintf("users[%d]", i)
+		if user.Username == "" {
+			problems.add(field+".username", "不能为空")
+		} else if usernames[user.Username] {
+			problems.add(field+".username", "用户名 %s 重复", user.Username)
+		}
+		usernames[user.Username] = true
+
+		validatePasswordField(problems, field+".password", user.Password)
+		if user.Role != "" && !ValidRole(user.Role) {
+			problems.add(field+".role", "无效的角色 %q（可选 viewer、deployer、admin）", user.Role)
+		}
+		for j, grant := range user.Projects {
+			grantField := fmt.Sprintf("%s.projects[%d]", field, j)
+			if grant.Project == "" {
+				problems.add(grantField+".project", "不能为空")
+			} else if !projectNames[grant.Project] {
+				problems.add(grantField+".project", "项目 %s 不存在", grant.Project)
+			}
+			if grant.Role != "" && !ValidRole(grant.Role) {
+				problems.add(grantField+".role", "无效的角色 %q（可选 viewer、deployer、admin）", grant.Role)
+			}
+		}
+	}
+}
+
+// validatePasswordField 密码必须是受支持的哈希
+func validatePasswordField(problems *ValidationErrors, field, password string) {
+	if password == "" {
+		problems.add(field, "不能为空")
+	} else if !IsPasswordHash(password) {
+		problems.add(field, "不是 bcrypt/argon2id 哈希，请使用 gover hash-password 生成")
+	}
+}
+
+// validateProjects 检查项目名称、环境、路径、钩子和健康检查
+func (c *Config) validateProjects(problems *ValidationErrors) {
+	names := map[string]bool{}
+	for i, project := range c.Projects {
+		field := fmt.Sprintf("projects[%d]", i)
+		if project.Name == "" {
+			problems.add(field+".name", "不能为空")
+		} else if names[project.Name] {
+			problems.add(field+".name", "项目名称 %s 重复", project.Name)
+		}
+		names[project.Name] = true
+
+		if !ValidEnvironment(project.Environment) {
+			problems.add(field+".environment", "无效的环境 %s（可选 %s）", project.Environment, strings.Join(Environments, "、"))
+		}
+
+		if !ValidGitBackend(project.GitBackend) {
+			problems.add(field+".git_backend", "无效的 Git 后端 %s（可选 %s、%s）", project.GitBackend, GitBackendExec, GitBackendGoGit)
+		}
+
+		if !ValidDirtyPolicy(project.DirtyPolicy) {
+			problems.add(field+".dirty_policy", "无效的检出策略 %s（可选 %s、%s、%s）", project.DirtyPolicy, DirtyPolicyRefuse, DirtyPolicyStash, DirtyPolicyReset)
+		} else if project.DirtyPolicy == DirtyPolicyStash && project.GitBackend == GitBackendGoGit {
+			problems.add(field+".dirty_policy", "go-git 后端不支持 stash，请使用 refuse 或 reset")
+		}
+
+		// 路径不存在只是警告（见 Warnings），一个未挂载的目录不应阻止启动和重新加载
+		if project.Path == "" {
+			problems.add(field+".path", "不能为空")
+		}
+
+		validateHookSteps(problems, field+".pre_checkout", project.PreCheckout)
+		validateHookSteps(problems, field+".post_checkout", project.PostCheckout)
+		if project.HealthCheck != nil {
+			validateHealthCheck(problems, field+".health_check", project.HealthCheck)
+		}
+	}
+}
+
+// validateHookSteps 检查钩子步骤
+func validateHookSteps(problems *ValidationErrors, field string, steps []HookStep) {
+	for i, step := range steps {
+		stepField := fmt.Sprintf("%s[%d]", field, i)
+		if strings.TrimSpace(step.Command) == "" {
+			problems.add(stepField+".command", "不能为空")
+		}
+		if step.Timeout < 0 {
+			problems.add(stepField+".timeout", "不能为负数")
+		}
+	}
+}
+
+// validateHealthCheck 检查健康检查配置
+func validateHealthCheck(problems *ValidationErrors, field string, check *HealthCheck) {
+	if check.URL == "" {
+		problems.add(field+".url", "不能为空")
+	} else if u, err := url.Parse(check.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems.add(field+".url", "不是有效的 http/https 地址: %s", check.URL)
+	}
+	if check.ExpectedStatus != 0 && (check.ExpectedStatus < 100 || check.ExpectedStatus > 599) {
+		problems.add(field+".expected_status", "无效的 HTTP 状态码 %d", check.ExpectedStatus)
+	}
+	validateNonNegative(problems, field, []namedInt{
+		{"retries", check.Retries},
+		{"interval", check.Interval},
+		{"timeout", check.Timeout},
+	})
+}
+
+// validateSecurity 检查会话密钥和超时设置
+func (c *Config) validateSecurity(problems *ValidationErrors) {
+	security := c.Security
+	// 为空或使用公开默认值时自动生成随机密钥，不视为错误
+	if !c.GeneratesSessionSecret() && len(security.SessionSecret) < minSessionSecretLength {
+		problems.add("security.session_secret", "至少需要 %d 个字符（留空则自动生成随机密钥）", minSessionSecretLength)
+	}
+	for i, previous := range security.PreviousSessionSecrets {
+		if previous == "" {
+			problems.add(fmt.Sprintf("security.previous_session_secrets[%d]", i), "不能为空")
+		}
+	}
+	if security.SessionTimeout <= 0 {
+		problems.add("security.session_timeout", "必须大于 0")
+	}
+	validateNonNegative(problems, "security", []namedInt{
+		{"remember_me_days", security.RememberMeDays},
+		{"login_max_attempts", security.LoginMaxAttempts},
+		{"login_max_attempts_per_ip", security.LoginMaxAttemptsPerIP},
+		{"login_backoff", security.LoginBackoff},
+		{"login_lockout", security.LoginLockout},
+	})
+}
+
+// namedInt 字段名和值，按顺序检查以保证输出稳定
+type namedInt struct {
+	name  string
+	value int
+}
+
+// validateNonNegative 检查一组数值字段不能为负数
+func validateNonNegative(problems *ValidationErrors, prefix string, fields []namedInt) {
+	for _, field := range fields {
+		if field.value < 0 {
+			problems.add(prefix+"."+field.name, "不能为负数")
+		}
+	}
+}
+
+// validateTokens 检查配置文件中的 API 令牌
+func (c *Config) validateTokens(problems *ValidationErrors) {
+	names := map[string]bool{}
+	hashes := map[string]bool{}
+	for i, token := range c.APITokens {
+		field := fmt.Sprintf("api_tokens[%d]", i)
+		if token.Name == "" {
+			problems.add(field+".name", "不能为空")
+		} else if names[token.Name] {
+			problems.add(field+".name", "令牌名称 %s 重复", token.Name)
+		}
+		names[token.Name] = true
+		if !isSHA256Hex(token.Hash) {
+			problems.add(field+".hash", "必须是 64 位十六进制 SHA-256 哈希")
+		} else if hash := strings.ToLower(token.Hash); hashes[hash] {
+			problems.add(field+".hash", "与其他令牌的哈希重复")
+		} else {
+			hashes[hash] = true
+		}
+	}
+}
+
+// isSHA256Hex 是否为 SHA-256 十六进制字符串
+func isSHA256Hex(value string) bool {
+	if len(value) != 64 {
+		return false
+	}
+	for _, r := range strings.ToLower(value) {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"crypto/sha256"
+	"log"
+	"os"
+	"time"
+)
+
+// DefaultConfigWatchInterval 配置文件变更检查间隔
+const DefaultConfigWatchInterval = 2 * time.Second
+
+// configFileState 配置文件的状态，用于判断文件是否变化
+type configFileState struct {
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+// readConfigFileState 读取配置文件当前状态
+func readConfigFileState(path string, previous configFileState) (configFileState, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return configFileState{}, err
+	}
+	state := configFileState{modTime: stat.ModTime(), size: stat.Size(), sum: previous.sum}
+	if state.modTime.Equal(previous.modTime) && state.size == previous.size {
+		return state, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return configFileState{}, err
+	}
+	state.sum = sha256.Sum256(data)
+	return state, nil
+}
+
+// WatchConfig 轮询配置文件，内容变化时重新加载；校验失败时记录错误并继续使用原配置
+// onReload 在新配置生效后调用，参数为旧配置和新配置
+func WatchConfig(interval time.Duration, onReload func(old, current *Config)) {
+	path := ConfigPath()
+	state, _ := readConfigFileState(path, configFileState{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			next, err := readConfigFileState(path, state)
+			if err != nil {
+				// 文件暂时不存在（如编辑器替换文件过程中），下次再检查
+				continue
+			}
+			changed := next.sum != state.sum
+			state = next
+			if !changed {
+				continue
+			}
+
+			old := GetConfig()
+			config, err := ReloadConfig()
+			if err != nil {
+				log.Printf("❌ 配置文件 %s 已修改但未生效: %v", path, err)
+				continue
+			}
+			log.Printf("🔄 配置已重新加载，共有 %d 个项目", len(config.Projects))
+			if onReload != nil {
+				onReload(old, config)
+			}
+		}
+	}()
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"gover/controllers"
+	"gover/models"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// setupListeners 根据配置设置 HTTP 或 HTTPS 监听，启用 TLS 时可选启动 HTTP→HTTPS 重定向服务
+func setupListeners() error {
+	server := models.GetConfig().Server
+	web.BConfig.Listen.HTTPAddr = server.Host
+	web.BConfig.Listen.HTTPPort = server.Port
+
+	if !server.TLS.Enabled {
+		return nil
+	}
+
+	tlsConfig := server.TLS
+	if tlsConfig.CertFile == "" || tlsConfig.KeyFile == "" {
+		return fmt.Errorf("启用 TLS 时必须配置 server.tls.cert_file 和 server.tls.key_file")
+	}
+	minVersion, err := tlsConfig.MinTLSVersion()
+	if err != nil {
+		return err
+	}
+	// 提前加载证书，配置错误时立即退出而不是在后台监听时失败
+	if _, err := tls.LoadX509KeyPair(tlsConfig.CertFile, tlsConfig.KeyFile); err != nil {
+		return fmt.Errorf("加载 TLS 证书失败: %v", err)
+	}
+
+	web.BConfig.Listen.EnableHTTP = false
+	web.BConfig.Listen.EnableHTTPS = true
+	web.BConfig.Listen.HTTPSAddr = server.Host
+	web.BConfig.Listen.HTTPSPort = server.Port
+	web.BConfig.Listen.HTTPSCertFile = tlsConfig.CertFile
+	web.BConfig.Listen.HTTPSKeyFile = tlsConfig.KeyFile
+	web.BeeApp.Server.TLSConfig = &tls.Config{MinVersion: minVersion}
+
+	if tlsConfig.RedirectPort > 0 {
+		if err := runHTTPSRedirect(server.Host, tlsConfig.RedirectPort, server.Port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHTTPSRedirect 监听 HTTP 端口，将所有请求重定向到 HTTPS
+// 端口在返回前完成监听，监听失败时返回错误；之后重定向服务出错只记录日志，不影响 HTTPS 服务
+func runHTTPSRedirect(host string, port, httpsPort int) error {
+	redirect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			target = h
+		}
+		if httpsPort != 443 {
+			target = net.JoinHostPort(target, strconv.Itoa(httpsPort))
+		}
+		// 308 保持请求方法不变
+		http.Redirect(w, r, "https://"+target+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("HTTP 重定向服务启动失败: %v", err)
+	}
+	fmt.Printf("↪️ HTTP 重定向服务: http://%s → https\n", addr)
+
+	redirectServer := &http.Server{
+		Addr:              addr,
+		Handler:           redirect,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := redirectServer.Serve(listener); err != nil {
+			fmt.Printf("⚠️ HTTP 重定向服务已停止，HTTPS 服务继续运行: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// serverURL 返回服务访问地址，用于启动提示
+func serverURL() string {
+	server := models.GetConfig().Server
+	scheme := "http"
+	if server.TLS.Enabled {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(server.Host, strconv.Itoa(server.Port)))
+}
+
+// onConfigReload 配置热重载后的处理：重建依赖配置的会话存储，提示需要重启才能生效的配置
+func onConfigReload(old, current *models.Config) {
+	if old.Security.SessionSecret != current.Security.SessionSecret ||
+		!slices.Equal(old.Security.PreviousSessionSecrets, current.Security.PreviousSessionSecrets) ||
+		old.DataDir != current.DataDir {
+		// 会话密钥或数据目录变化后按新配置重建会话存储，未保留在 previous_session_secrets 中的旧密钥签发的会话将失效
+		controllers.ResetSessionStore()
+		fmt.Printf("🔑 会话配置已变更，会话存储已重建\n")
+	}
+	if old.Server != current.Server {
+		fmt.Printf("⚠️ server 配置（监听地址、端口、TLS）的修改需要重启服务后生效\n")
+	}
+}
+
+// checkSessionKeys 加载（必要时生成）会话签名密钥，未配置安全的 session_secret 时给出醒目提示
+func checkSessionKeys() error {
+	config := models.GetConfig()
+	if _, err := config.SessionKeys(); err != nil {
+		return err
+	}
+	if !config.GeneratesSessionSecret() {
+		return nil
+	}
+
+	if config.Security.SessionSecret != "" {
+		fmt.Printf("⚠️⚠️⚠️ security.session_secret 使用了公开的默认值，任何人都可以伪造会话 Cookie，已忽略该值\n")
+	} else {
+		fmt.Printf("⚠️ 未配置 security.session_secret\n")
+	}
+	fmt.Printf("🔑 使用自动生成的随机会话密钥: %s（请妥善保管，可运行 gover rotate-secret 轮换）\n", config.SessionKeyFilePath())
+	return nil
+}